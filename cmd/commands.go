@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"kuperparser/internal/config"
+	"kuperparser/internal/kuper"
+	"kuperparser/internal/logic"
+)
+
+func runCrawl(ctx context.Context, cfg *config.Config, _ *options, _ []string) error {
+	summary, err := logic.Run(ctx, cfg)
+	if summary != nil {
+		if werr := summary.WriteTable(os.Stdout); werr != nil {
+			return werr
+		}
+	}
+	return err
+}
+
+func runValidateConfig(ctx context.Context, cfg *config.Config, o *options, _ []string) error {
+	if err := logic.Validate(ctx, cfg); err != nil {
+		return err
+	}
+	fmt.Printf("%s: ok\n", o.config)
+	return nil
+}
+
+func runCategories(ctx context.Context, cfg *config.Config, o *options, _ []string) error {
+	categories, err := logic.Categories(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	if o.format == "json" {
+		return printJSON(categories)
+	}
+
+	// дерево по parent_id; категории с неизвестным родителем считаются корневыми
+	byID := make(map[int]bool, len(categories))
+	for _, c := range categories {
+		byID[c.ID] = true
+	}
+	children := make(map[int][]kuper.Category)
+	for _, c := range categories {
+		parent := c.ParentID
+		if !byID[parent] {
+			parent = 0
+		}
+		children[parent] = append(children[parent], c)
+	}
+
+	var walk func(parent, depth int)
+	walk = func(parent, depth int) {
+		for _, c := range children[parent] {
+			fmt.Printf("%s%s  [slug=%s id=%d товаров=%d]\n", strings.Repeat("  ", depth), c.Name, c.Slug, c.ID, c.ProductsCount)
+			if c.ID != parent {
+				walk(c.ID, depth+1)
+			}
+		}
+	}
+	walk(0, 0)
+	return nil
+}
+
+func runStore(ctx context.Context, cfg *config.Config, o *options, _ []string) error {
+	info, err := logic.Store(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	if o.format == "json" {
+		return printJSON(info)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "id\t%d\n", info.StoreID)
+	fmt.Fprintf(tw, "магазин\t%s\n", info.StoreName)
+	fmt.Fprintf(tw, "сеть\t%s\n", info.RetailerName)
+	fmt.Fprintf(tw, "адрес\t%s\n", info.StoreAddress)
+	return tw.Flush()
+}
+
+func runSearch(ctx context.Context, cfg *config.Config, o *options, args []string) error {
+	query := strings.Join(args, " ")
+	if strings.TrimSpace(query) == "" {
+		return errors.New("search: не указан запрос, например: kuper search молоко")
+	}
+
+	hits, err := logic.Search(ctx, cfg, query)
+	if err != nil && len(hits) == 0 {
+		return err
+	}
+	if o.format == "json" {
+		if perr := printJSON(hits); perr != nil {
+			return perr
+		}
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ОТДЕЛ\tТОВАР\tЦЕНА\tССЫЛКА")
+	for _, h := range hits {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", h.Department, h.Name, h.Price, h.URL)
+	}
+	if ferr := tw.Flush(); ferr != nil {
+		return ferr
+	}
+	// часть отделов уже обойдена — печатаем найденное и возвращаем ошибку
+	return err
+}
+
+func runProxyCheck(ctx context.Context, cfg *config.Config, o *options, _ []string) error {
+	results, err := logic.CheckProxies(ctx, cfg, min(o.timeout, 30*time.Second))
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].OK() && !results[j].OK() })
+
+	failed := 0
+	for _, r := range results {
+		if !r.OK() {
+			failed++
+		}
+	}
+
+	if o.format == "json" {
+		type row struct {
+			Proxy     string `json:"proxy"`
+			OK        bool   `json:"ok"`
+			Status    int    `json:"status,omitempty"`
+			LatencyMS int64  `json:"latency_ms"`
+			Blocked   string `json:"blocked,omitempty"`
+			Error     string `json:"error,omitempty"`
+		}
+		rows := make([]row, 0, len(results))
+		for _, r := range results {
+			e := ""
+			if r.Err != nil {
+				e = r.Err.Error()
+			}
+			rows = append(rows, row{r.Proxy, r.OK(), r.Status, r.Latency.Milliseconds(), r.Blocked, e})
+		}
+		if err := printJSON(rows); err != nil {
+			return err
+		}
+	} else {
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ПРОКСИ\tСТАТУС\tВРЕМЯ\tРЕЗУЛЬТАТ")
+		for _, r := range results {
+			result := "ok"
+			switch {
+			case r.Err != nil:
+				result = r.Err.Error()
+			case r.Blocked != "":
+				result = "антибот: " + r.Blocked
+			case !r.OK():
+				result = "плохой статус"
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", r.Proxy, r.Status, r.Latency.Round(time.Millisecond), result)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("не работают %d из %d прокси", failed, len(results))
+	}
+	return nil
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
@@ -0,0 +1,203 @@
+// kupermock локальная заглушка API Kuper для разработки и CI без сети.
+//
+// Отдаёт категории, магазин и страницы отделов из fixture JSON и может вносить задержки и сбои:
+//
+//	go run ./cmd/kupermock -addr :8081 -error-rate 0.1 -latency 500ms
+//
+// и в config.yaml: kuper.base_url: http://localhost:8081, proxy.mode: disabled
+package main
+
+import (
+	"embed"
+	"flag"
+	"io"
+	"io/fs"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"kuperparser/internal/client"
+	"kuperparser/internal/logging"
+)
+
+//go:embed fixtures
+var embedded embed.FS
+
+func main() {
+	var (
+		addr     = flag.String("addr", ":8081", "адрес сервера")
+		fixtures = flag.String("fixtures", "", "директория с fixture JSON; пусто — встроенные fixtures")
+		logLevel = flag.String("log-level", "info", "debug | info | warn | error")
+
+		chaos      client.ChaosConfig
+		retryAfter int
+	)
+	flag.DurationVar(&chaos.Latency, "latency", 0, "максимальная задержка ответа (случайная от 0)")
+	flag.Float64Var(&chaos.LatencyRate, "latency-rate", 1, "доля ответов с задержкой")
+	flag.Float64Var(&chaos.ErrorRate, "error-rate", 0, "доля обрывов соединения")
+	flag.Float64Var(&chaos.RateLimitRate, "rate-limit-rate", 0, "доля ответов 429")
+	flag.IntVar(&retryAfter, "retry-after", 1, "Retry-After для 429, секунды")
+	flag.Float64Var(&chaos.ServerErrorRate, "server-error-rate", 0, "доля ответов 5xx")
+	flag.Float64Var(&chaos.ChallengeRate, "challenge-rate", 0, "доля html-страниц капчи")
+	flag.Float64Var(&chaos.TruncateRate, "truncate-rate", 0, "доля ответов с оборванным телом")
+	flag.Int64Var(&chaos.Seed, "seed", 0, "seed последовательности сбоев, 0 — случайно")
+	flag.Parse()
+	chaos.RetryAfter = time.Duration(retryAfter) * time.Second
+
+	logger, err := logging.New(os.Stderr, *logLevel, "text")
+	if err != nil {
+		log.Fatalf("log-level: %v", err)
+	}
+	if err := chaos.Validate(); err != nil {
+		log.Fatalf("параметры сбоев: %v", err)
+	}
+
+	var fsys fs.FS
+	if *fixtures != "" {
+		fsys = os.DirFS(*fixtures)
+	} else {
+		fsys, _ = fs.Sub(embedded, "fixtures")
+	}
+
+	var h http.Handler = newMux(fsys)
+	if chaos.Enabled() {
+		h = chaosHandler(h, chaos)
+	}
+	h = logRequests(h, logger)
+
+	logger.Info("kupermock слушает", "addr", *addr, "fixtures", *fixtures)
+	if err := http.ListenAndServe(*addr, h); err != nil {
+		log.Fatalf("Ошибка сервера: %v", err)
+	}
+}
+
+// newMux маршруты API; fixture-файлы:
+//
+//	categories.json                 ответ /api/v3/stores/{id}/categories
+//	store.json                      ответ /api/stores/{id}
+//	departments/{slug}/{page}.json  страница отдела; нет файла страницы — пустой список, нет директории — 404
+func newMux(fsys fs.FS) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	// главная страница для прогрева сессии
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "kupermock", Path: "/"})
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, "<!DOCTYPE html><html><head><title>kupermock</title></head><body></body></html>")
+	})
+
+	// страница магазина для прогрева: выставляет cookie выбранного магазина
+	mux.HandleFunc("GET /stores/{id}", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "store_id", Value: r.PathValue("id"), Path: "/"})
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, "<!DOCTYPE html><html><head><title>kupermock store</title></head><body></body></html>")
+	})
+
+	mux.HandleFunc("GET /api/v3/stores/{id}/categories", func(w http.ResponseWriter, r *http.Request) {
+		serveFixture(w, fsys, "categories.json")
+	})
+
+	mux.HandleFunc("GET /api/stores/{id}", func(w http.ResponseWriter, r *http.Request) {
+		serveFixture(w, fsys, "store.json")
+	})
+
+	mux.HandleFunc("GET /api/v3/stores/{id}/departments/{slug}", func(w http.ResponseWriter, r *http.Request) {
+		dir := path.Join("departments", r.PathValue("slug"))
+		if _, err := fs.Stat(fsys, dir); err != nil {
+			writeJSON(w, http.StatusNotFound, `{"code":"department_not_found","message":"department not found"}`)
+			return
+		}
+
+		page, err := strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil || page < 1 {
+			page = 1
+		}
+		name := path.Join(dir, strconv.Itoa(page)+".json")
+		if _, err := fs.Stat(fsys, name); err != nil {
+			writeJSON(w, http.StatusOK, `{"products":[]}`)
+			return
+		}
+		serveFixture(w, fsys, name)
+	})
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusNotFound, `{"code":"not_found","message":"unknown endpoint"}`)
+	})
+
+	return mux
+}
+
+func serveFixture(w http.ResponseWriter, fsys fs.FS, name string) {
+	b, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, `{"code":"fixture_error","message":`+strconv.Quote(err.Error())+`}`)
+		return
+	}
+	writeJSON(w, http.StatusOK, string(b))
+}
+
+func writeJSON(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	io.WriteString(w, body)
+}
+
+// chaosHandler вносит сбои тем же client.ChaosTransport, что и в парсере;
+// ошибка соединения и оборванное тело — обрыв соединения на стороне сервера
+func chaosHandler(next http.Handler, cfg client.ChaosConfig) http.Handler {
+	t := client.NewChaosTransport(client.TransportFunc(func(req *http.Request) (*http.Response, error) {
+		rec := httptest.NewRecorder()
+		next.ServeHTTP(rec, req)
+		return rec.Result(), nil
+	}), cfg)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp, err := t.Do(r)
+		if err != nil {
+			panic(http.ErrAbortHandler)
+		}
+		defer resp.Body.Close()
+
+		for k, v := range resp.Header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(resp.StatusCode)
+		if _, err := io.Copy(w, resp.Body); err != nil {
+			panic(http.ErrAbortHandler)
+		}
+	})
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func logRequests(next http.Handler, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			rec := recover()
+			status := sw.status
+			if rec != nil {
+				status = 0 // соединение оборвано
+			}
+			logger.Info("запрос", "method", r.Method, "path", r.URL.RequestURI(), "status", status, "duration", time.Since(started))
+			if rec != nil {
+				panic(rec)
+			}
+		}()
+		next.ServeHTTP(sw, r)
+	})
+}
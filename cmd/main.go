@@ -2,23 +2,182 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"kuperparser/internal/config"
+	"kuperparser/internal/logging"
 	"kuperparser/internal/logic"
 )
 
+const usage = `Использование: kuper <команда> [флаги]
+
+Команды:
+  crawl            обойти отделы и записать файлы с товарами (по умолчанию)
+  categories       дерево отделов магазина
+  store            информация о магазине
+  search <запрос>  найти товары по названию в отделах (--dept или departments.names, иначе во всех)
+  validate-config  проверить конфиг и собрать транспорт без запросов к сайту
+  proxy-check      открыть kuper.base_url через каждый прокси из конфига
+
+Флаги (указанные флаги перекрывают значения из конфига):
+`
+
+// exitPartial код выхода, когда crawl записал не все отделы или страницы (failure.policy skip-department|skip-page);
+// 1 — запуск не удался, 2 — ошибка в аргументах
+const exitPartial = 3
+
+// options общие флаги всех команд
+type options struct {
+	config  string
+	store   int
+	depts   []string
+	all     bool
+	output  string
+	format  string
+	timeout time.Duration
+}
+
+// command выполняет подкоманду; args — позиционные аргументы после флагов
+type command func(ctx context.Context, cfg *config.Config, o *options, args []string) error
+
+var commands = map[string]command{
+	"crawl":           runCrawl,
+	"categories":      runCategories,
+	"store":           runStore,
+	"search":          runSearch,
+	"validate-config": runValidateConfig,
+	"proxy-check":     runProxyCheck,
+}
+
 func main() {
-	cfg, err := config.Load("config.yaml")
+	args := os.Args[1:]
+
+	// без команды (или сразу с флагами) — crawl, как раньше
+	name := "crawl"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" {
+		printUsage(newFlagSet("help", &options{}))
+		return
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "неизвестная команда %q\n\n", name)
+		printUsage(newFlagSet(name, &options{}))
+		os.Exit(2)
+	}
+
+	var o options
+	fs := newFlagSet(name, &o)
+	posArgs, err := parseInterspersed(fs, args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load(o.config)
+	if err != nil {
+		log.Fatalf("Ошибка чтения %s: %v", o.config, err)
+	}
+	applyOverrides(fs, cfg, &o)
+	if name == "crawl" || name == "validate-config" {
+		if o.format != "" {
+			cfg.Output.Format = o.format
+		}
+	}
+	// отделы нужны только обходу; store, categories, search и proxy-check работают без них
+	validate := cfg.Validate
+	if name == "crawl" || name == "validate-config" {
+		validate = cfg.ValidateCrawl
+	}
+	if err := validate(); err != nil {
+		log.Fatalf("%s: %v", o.config, err)
+	}
+
+	logger, err := logging.New(os.Stderr, cfg.Log.Level, cfg.Log.Format)
 	if err != nil {
-		log.Fatalf("Ошибка чтения config.yaml: %v", err)
+		log.Fatalf("Ошибка настройки логов: %v", err)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, o.timeout)
 	defer cancel()
+	ctx = logging.NewContext(ctx, logger)
 
-	if err := logic.Run(ctx, cfg); err != nil {
+	if err := cmd(ctx, cfg, &o, posArgs); err != nil {
+		var partial *logic.PartialError
+		if errors.As(err, &partial) {
+			log.Printf("Обход выполнен частично: %v", err)
+			os.Exit(exitPartial)
+		}
 		log.Fatalf("Ошибка выполнения: %v", err)
 	}
 }
+
+func newFlagSet(name string, o *options) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&o.config, "config", "config.yaml", "путь к конфигу")
+	fs.IntVar(&o.store, "store", 0, "id магазина (kuper.store_id)")
+	fs.Func("dept", "название отдела (departments.names); можно указать несколько раз", func(s string) error {
+		o.depts = append(o.depts, s)
+		return nil
+	})
+	fs.BoolVar(&o.all, "all", false, "все отделы магазина (departments.all), с учётом departments.exclude")
+	fs.StringVar(&o.output, "output", "", "директория для файлов (output.directory)")
+	fs.StringVar(&o.format, "format", "", "crawl: csv|jsonl (output.format); остальные команды: text|json")
+	fs.DurationVar(&o.timeout, "timeout", 2*time.Minute, "общий лимит времени работы")
+	fs.Usage = func() { printUsage(fs) }
+	return fs
+}
+
+func printUsage(fs *flag.FlagSet) {
+	fmt.Fprint(os.Stderr, usage)
+	fs.SetOutput(os.Stderr)
+	fs.PrintDefaults()
+}
+
+// parseInterspersed разрешает флаги после позиционных аргументов: kuper search молоко --store 1
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var pos []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return pos, nil
+		}
+		pos = append(pos, args[0])
+		args = args[1:]
+	}
+}
+
+// applyOverrides переносит в конфиг только явно указанные флаги
+func applyOverrides(fs *flag.FlagSet, cfg *config.Config, o *options) {
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "store":
+			cfg.Kuper.StoreID = o.store
+		case "all":
+			cfg.Departments.All = o.all
+		case "dept":
+			// отделы из флагов важнее departments.all из конфига
+			cfg.Departments.Names = o.depts
+			cfg.Departments.All = o.all
+		case "output":
+			cfg.Output.Directory = o.output
+		}
+	})
+}
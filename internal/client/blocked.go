@@ -0,0 +1,198 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrBlocked ответ — страница антибота/капчи, а не данные API
+var ErrBlocked = errors.New("заблокировано антиботом")
+
+// BlockedError подробности блокировки; errors.Is(err, ErrBlocked) == true
+type BlockedError struct {
+	URL    string
+	Status int
+	Reason string
+	Proxy  string // host:port без логина/пароля, пусто для прямых запросов
+}
+
+func (e *BlockedError) Error() string {
+	s := fmt.Sprintf("блокировка антиботом: reason=%s status=%d url=%s", e.Reason, e.Status, e.URL)
+	if e.Proxy != "" {
+		s += " proxy=" + e.Proxy
+	}
+	return s
+}
+
+func (e *BlockedError) Is(target error) bool { return target == ErrBlocked }
+
+// BlockPolicy что делать при обнаружении антибота
+type BlockPolicy string
+
+const (
+	BlockRotate   BlockPolicy = "rotate"   // сразу повторить запрос через следующий прокси
+	BlockCooldown BlockPolicy = "cooldown" // как rotate + не использовать прокси CooldownFor
+	BlockAbort    BlockPolicy = "abort"    // вернуть ошибку без повторов
+)
+
+type BlockConfig struct {
+	Policy      BlockPolicy
+	CooldownFor time.Duration
+}
+
+// маркеры страниц проверки/капчи в теле ответа (в нижнем регистре)
+var blockBodyMarkers = []string{
+	"captcha",
+	"qrator",
+	"ddos-guard",
+	"servicepipe",
+	"challenge-platform",
+	"cf-chl",
+	"verify you are human",
+	"подтвердите, что вы не робот",
+	"доступ ограничен",
+	"access denied",
+}
+
+// маркеры в адресе, на который редиректит антибот
+var blockURLMarkers = []string{"captcha", "challenge", "/blocked", "__qrator", "ddos-guard"}
+
+const blockPeekLimit = 64 * 1024
+
+// BlockedURL проверяет адрес (например, цель редиректа) на страницу капчи
+func BlockedURL(u *url.URL) (string, bool) {
+	s := strings.ToLower(u.Host + u.Path)
+	for _, m := range blockURLMarkers {
+		if strings.Contains(s, m) {
+			return "redirect:" + m, true
+		}
+	}
+	return "", false
+}
+
+// BlockedBody проверяет тело ответа на страницу антибота; wantJSON — запрос был к API
+func BlockedBody(contentType string, body []byte, wantJSON bool) (string, bool) {
+	lower := strings.ToLower(string(body))
+	for _, m := range blockBodyMarkers {
+		if strings.Contains(lower, m) {
+			return "marker:" + m, true
+		}
+	}
+
+	isHTML := strings.Contains(strings.ToLower(contentType), "text/html") ||
+		strings.HasPrefix(strings.TrimSpace(lower), "<!doctype html") ||
+		strings.HasPrefix(strings.TrimSpace(lower), "<html")
+	if wantJSON && isHTML {
+		return "unexpected html", true
+	}
+	return "", false
+}
+
+// DetectBlocked проверяет ответ; тело читается не полностью и восстанавливается для дальнейшего чтения
+func DetectBlocked(resp *http.Response) (string, bool) {
+	if resp.Request != nil {
+		if reason, ok := BlockedURL(resp.Request.URL); ok {
+			return reason, true
+		}
+	}
+
+	ct := resp.Header.Get("Content-Type")
+	wantJSON := resp.Request != nil && strings.HasPrefix(resp.Request.URL.Path, "/api/")
+
+	// json ответ не разбираем: проверяем только html, ответы без типа и коды, которыми отвечают антиботы
+	suspicious := !strings.Contains(ct, "json") ||
+		resp.StatusCode == http.StatusForbidden ||
+		resp.StatusCode == http.StatusUnauthorized
+	if !suspicious {
+		return "", false
+	}
+
+	peek, err := io.ReadAll(io.LimitReader(resp.Body, blockPeekLimit))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(peek), resp.Body), resp.Body}
+	if err != nil {
+		return "", false
+	}
+
+	return BlockedBody(ct, peek, wantJSON)
+}
+
+// BlockDetectTransport превращает страницы антибота в *BlockedError и, при политике cooldown,
+// перестаёт пропускать запросы после блокировки
+type BlockDetectTransport struct {
+	Base  Transport
+	Cfg   BlockConfig
+	Proxy string
+
+	mu    sync.Mutex
+	until time.Time
+}
+
+func (t *BlockDetectTransport) Do(req *http.Request) (*http.Response, error) {
+	if t.Cfg.Policy == BlockCooldown {
+		t.mu.Lock()
+		until := t.until
+		t.mu.Unlock()
+		if time.Now().Before(until) {
+			return nil, &BlockedError{URL: RedactURL(req.URL), Reason: "пауза после блокировки до " + until.Format(time.RFC3339), Proxy: t.Proxy}
+		}
+	}
+
+	resp, err := t.Base.Do(req)
+	if err != nil {
+		var be *BlockedError
+		if errors.As(err, &be) {
+			be.Proxy = t.Proxy
+			t.cooldown()
+		}
+		return nil, err
+	}
+
+	reason, blocked := DetectBlocked(resp)
+	if !blocked {
+		return resp, nil
+	}
+
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 32*1024))
+	resp.Body.Close()
+	t.cooldown()
+
+	return nil, &BlockedError{URL: RedactURL(req.URL), Status: resp.StatusCode, Reason: reason, Proxy: t.Proxy}
+}
+
+func (t *BlockDetectTransport) cooldown() {
+	if t.Cfg.Policy != BlockCooldown || t.Cfg.CooldownFor <= 0 {
+		return
+	}
+	t.mu.Lock()
+	t.until = time.Now().Add(t.Cfg.CooldownFor)
+	t.mu.Unlock()
+}
+
+// ProxyBlockDetect обнаружение антибота отдельно для каждого прокси
+func ProxyBlockDetect(cfg BlockConfig) ProxyLayer {
+	return func(proxy *url.URL, base Transport) Transport {
+		return &BlockDetectTransport{Base: base, Cfg: cfg, Proxy: proxy.Host}
+	}
+}
+
+// ParseBlockPolicy разбирает политику из конфига; пусто — rotate
+func ParseBlockPolicy(s string) (BlockPolicy, error) {
+	switch p := BlockPolicy(strings.ToLower(strings.TrimSpace(s))); p {
+	case "":
+		return BlockRotate, nil
+	case BlockRotate, BlockCooldown, BlockAbort:
+		return p, nil
+	default:
+		return "", fmt.Errorf("неизвестная политика антибота %q (ожидается rotate|cooldown|abort)", s)
+	}
+}
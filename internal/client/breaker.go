@@ -0,0 +1,208 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen цепь разомкнута, запрос не отправлялся
+var ErrCircuitOpen = errors.New("circuit breaker разомкнут")
+
+// CircuitOpenError возвращается вместо запроса, пока breaker для ключа (хост или прокси) разомкнут
+type CircuitOpenError struct {
+	Key   string
+	Until time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker разомкнут: key=%s until=%s", e.Key, e.Until.Format(time.RFC3339))
+}
+
+func (e *CircuitOpenError) Is(target error) bool { return target == ErrCircuitOpen }
+
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+type BreakerConfig struct {
+	FailureThreshold int           // сколько неудач подряд размыкают цепь, 0 — breaker выключен
+	OpenTimeout      time.Duration // сколько цепь остаётся разомкнутой до пробных запросов
+	HalfOpenRequests int           // сколько пробных запросов пропускать одновременно в half-open
+}
+
+func (c BreakerConfig) Enabled() bool { return c.FailureThreshold > 0 }
+
+// CircuitBreaker классический closed/open/half-open автомат
+type CircuitBreaker struct {
+	key string
+	cfg BreakerConfig
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	until    time.Time
+	probes   int
+}
+
+func NewCircuitBreaker(key string, cfg BreakerConfig) *CircuitBreaker {
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 30 * time.Second
+	}
+	if cfg.HalfOpenRequests <= 0 {
+		cfg.HalfOpenRequests = 1
+	}
+	return &CircuitBreaker{key: key, cfg: cfg}
+}
+
+// Allow решает, можно ли отправить запрос; при разрешении вызывающий обязан вызвать Record
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Now().Before(b.until) {
+			return &CircuitOpenError{Key: b.key, Until: b.until}
+		}
+		b.state = BreakerHalfOpen
+		b.probes = 0
+		fallthrough
+	case BreakerHalfOpen:
+		if b.probes >= b.cfg.HalfOpenRequests {
+			return &CircuitOpenError{Key: b.key, Until: time.Now().Add(time.Second)}
+		}
+		b.probes++
+	}
+	return nil
+}
+
+// Record учитывает результат разрешённого запроса. Пока цепь разомкнута, результаты не учитываются:
+// это запросы, начатые до размыкания, и медленный успешный ответ не должен её замыкать
+func (b *CircuitBreaker) Record(outcome BreakerOutcome) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen {
+		return
+	}
+	if b.state == BreakerHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+
+	switch outcome {
+	case OutcomeSuccess:
+		// замыкается цепь только пробным запросом из half-open
+		b.failures = 0
+		b.state = BreakerClosed
+	case OutcomeFailure:
+		b.failures++
+		if b.state == BreakerHalfOpen || b.failures >= b.cfg.FailureThreshold {
+			b.state = BreakerOpen
+			b.until = time.Now().Add(b.cfg.OpenTimeout)
+		}
+	}
+}
+
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+type BreakerOutcome int
+
+const (
+	OutcomeIgnored BreakerOutcome = iota // отмена контекста и т.п. — не влияет на состояние
+	OutcomeSuccess
+	OutcomeFailure
+)
+
+// classifyOutcome: ошибки сети, 5xx и 403 (стена антибота) считаются отказом
+func classifyOutcome(resp *http.Response, err error) BreakerOutcome {
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrCircuitOpen) {
+			return OutcomeIgnored
+		}
+		return OutcomeFailure
+	}
+	if resp == nil {
+		return OutcomeIgnored
+	}
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusForbidden {
+		return OutcomeFailure
+	}
+	return OutcomeSuccess
+}
+
+func (b *CircuitBreaker) do(base Transport, req *http.Request) (*http.Response, error) {
+	if err := b.Allow(); err != nil {
+		return nil, err
+	}
+	resp, err := base.Do(req)
+	b.Record(classifyOutcome(resp, err))
+	return resp, err
+}
+
+// BreakerTransport держит отдельный breaker на каждый хост
+type BreakerTransport struct {
+	Base Transport
+
+	cfg      BreakerConfig
+	mu       sync.Mutex
+	breakers map[string]*CircuitBreaker
+}
+
+func NewBreakerTransport(base Transport, cfg BreakerConfig) *BreakerTransport {
+	return &BreakerTransport{
+		Base:     base,
+		cfg:      cfg,
+		breakers: make(map[string]*CircuitBreaker),
+	}
+}
+
+func (t *BreakerTransport) Do(req *http.Request) (*http.Response, error) {
+	return t.breakerFor(req.URL.Host).do(t.Base, req)
+}
+
+func (t *BreakerTransport) breakerFor(host string) *CircuitBreaker {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b, ok := t.breakers[host]
+	if !ok {
+		b = NewCircuitBreaker("host="+host, t.cfg)
+		t.breakers[host] = b
+	}
+	return b
+}
+
+// ProxyCircuitBreaker отдельный breaker на каждый прокси; разомкнутый прокси пропускается при ротации
+func ProxyCircuitBreaker(cfg BreakerConfig) ProxyLayer {
+	return func(proxy *url.URL, base Transport) Transport {
+		b := NewCircuitBreaker("proxy="+proxy.Host, cfg)
+		return TransportFunc(func(req *http.Request) (*http.Response, error) {
+			return b.do(base, req)
+		})
+	}
+}
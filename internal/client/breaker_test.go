@@ -0,0 +1,87 @@
+package client
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	b := NewCircuitBreaker("host=kuper.ru", BreakerConfig{FailureThreshold: 3, OpenTimeout: 20 * time.Millisecond})
+
+	fail := func() {
+		t.Helper()
+		if err := b.Allow(); err != nil {
+			t.Fatalf("Allow в состоянии %s: %v", b.State(), err)
+		}
+		b.Record(OutcomeFailure)
+	}
+
+	// неудачи подряд до порога; успех между ними обнуляет счётчик
+	fail()
+	fail()
+	b.Record(OutcomeSuccess)
+	fail()
+	fail()
+	if s := b.State(); s != BreakerClosed {
+		t.Fatalf("состояние %s до порога, ожидалось closed", s)
+	}
+	fail()
+	if s := b.State(); s != BreakerOpen {
+		t.Fatalf("состояние %s после 3 неудач подряд, ожидалось open", s)
+	}
+
+	err := b.Allow()
+	var openErr *CircuitOpenError
+	if !errors.As(err, &openErr) || !errors.Is(err, ErrCircuitOpen) || openErr.Key != "host=kuper.ru" {
+		t.Fatalf("Allow в open = %v, ожидалась CircuitOpenError", err)
+	}
+
+	// запрос, начатый до размыкания, не замыкает цепь
+	b.Record(OutcomeSuccess)
+	if s := b.State(); s != BreakerOpen {
+		t.Fatalf("состояние %s после успеха в open, ожидалось open", s)
+	}
+
+	// после OpenTimeout — один пробный запрос, неудача снова размыкает
+	time.Sleep(30 * time.Millisecond)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("пробный запрос не пропущен: %v", err)
+	}
+	if s := b.State(); s != BreakerHalfOpen {
+		t.Fatalf("состояние %s, ожидалось half-open", s)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("второй пробный запрос пропущен при half_open_requests=1: %v", err)
+	}
+	b.Record(OutcomeFailure)
+	if s := b.State(); s != BreakerOpen {
+		t.Fatalf("состояние %s после неудачного пробного запроса, ожидалось open", s)
+	}
+
+	// удачный пробный запрос замыкает цепь
+	time.Sleep(30 * time.Millisecond)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("пробный запрос не пропущен: %v", err)
+	}
+	b.Record(OutcomeSuccess)
+	if s := b.State(); s != BreakerClosed {
+		t.Fatalf("состояние %s после удачного пробного запроса, ожидалось closed", s)
+	}
+	if err := b.Allow(); err != nil {
+		t.Errorf("Allow в closed: %v", err)
+	}
+}
+
+func TestCircuitBreakerIgnored(t *testing.T) {
+	b := NewCircuitBreaker("proxy=10.0.0.1:8080", BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute})
+	for range 5 {
+		if err := b.Allow(); err != nil {
+			t.Fatal(err)
+		}
+		b.Record(OutcomeIgnored)
+	}
+	if s := b.State(); s != BreakerClosed {
+		t.Errorf("состояние %s после отменённых запросов, ожидалось closed", s)
+	}
+}
@@ -0,0 +1,186 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"kuperparser/internal/logging"
+)
+
+type CacheConfig struct {
+	Dir      string
+	TTL      time.Duration
+	MaxBytes int64 // 0 — без ограничения размера
+}
+
+func (c CacheConfig) Enabled() bool { return c.Dir != "" && c.TTL > 0 }
+
+type cacheEntry struct {
+	URL      string      `json:"url"`
+	StoredAt time.Time   `json:"stored_at"`
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header,omitempty"`
+	Body     string      `json:"body"`
+}
+
+// CacheTransport кэширует успешные GET-ответы на диске; устаревшие записи перепроверяются через ETag/Last-Modified
+type CacheTransport struct {
+	Base   Transport
+	Logger *slog.Logger // может быть nil
+	cfg    CacheConfig
+
+	mu sync.Mutex
+}
+
+func (t *CacheTransport) Do(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.Base.Do(req)
+	}
+
+	path := t.pathFor(req)
+	entry, ok := t.load(path)
+
+	if ok && time.Since(entry.StoredAt) < t.cfg.TTL {
+		logging.FromContext(req.Context(), t.Logger).Debug("ответ из кэша", "url", RedactURL(req.URL))
+		return cachedResponse(req, entry, "HIT"), nil
+	}
+
+	req2 := req
+	if ok {
+		etag := entry.Header.Get("ETag")
+		lastMod := entry.Header.Get("Last-Modified")
+		if etag != "" || lastMod != "" {
+			req2 = req.Clone(req.Context())
+			if etag != "" {
+				req2.Header.Set("If-None-Match", etag)
+			}
+			if lastMod != "" {
+				req2.Header.Set("If-Modified-Since", lastMod)
+			}
+		}
+	}
+
+	resp, err := t.Base.Do(req2)
+	if err != nil {
+		return nil, err
+	}
+
+	if ok && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		entry.StoredAt = time.Now()
+		t.store(req.Context(), path, entry)
+		return cachedResponse(req, entry, "REVALIDATED"), nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	t.store(req.Context(), path, cacheEntry{
+		URL:      redactURL(req.URL).String(),
+		StoredAt: time.Now(),
+		Status:   resp.StatusCode,
+		Header:   redactHeader(resp.Header),
+		Body:     string(body),
+	})
+
+	return resp, nil
+}
+
+func (t *CacheTransport) pathFor(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.Method + " " + req.URL.String()))
+	return filepath.Join(t.cfg.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (t *CacheTransport) load(path string) (cacheEntry, bool) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return cacheEntry{}, false
+	}
+	var e cacheEntry
+	if err := json.Unmarshal(b, &e); err != nil {
+		return cacheEntry{}, false
+	}
+	return e, true
+}
+
+// store ошибки кэша не должны ломать запрос — только логируем
+func (t *CacheTransport) store(ctx context.Context, path string, e cacheEntry) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		logging.FromContext(ctx, t.Logger).Warn("не удалось сериализовать запись кэша", "url", e.URL, "err", err)
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		logging.FromContext(ctx, t.Logger).Warn("не удалось записать кэш", "url", e.URL, "path", path, "err", err)
+		return
+	}
+	if t.cfg.MaxBytes > 0 {
+		t.evictLocked()
+	}
+}
+
+// evictLocked удаляет самые старые файлы, пока кэш больше MaxBytes
+func (t *CacheTransport) evictLocked() {
+	entries, err := os.ReadDir(t.cfg.Dir)
+	if err != nil {
+		return
+	}
+
+	type file struct {
+		path string
+		size int64
+		mod  time.Time
+	}
+	files := make([]file, 0, len(entries))
+	var total int64
+	for _, de := range entries {
+		info, err := de.Info()
+		if err != nil || info.IsDir() {
+			continue
+		}
+		files = append(files, file{filepath.Join(t.cfg.Dir, de.Name()), info.Size(), info.ModTime()})
+		total += info.Size()
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].mod.Before(files[j].mod) })
+
+	for _, f := range files {
+		if total <= t.cfg.MaxBytes {
+			return
+		}
+		if err := os.Remove(f.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		total -= f.size
+	}
+}
+
+func cachedResponse(req *http.Request, e cacheEntry, state string) *http.Response {
+	resp := newResponse(req, e.Status, e.Header, e.Body)
+	resp.Header.Set("X-Cache", state)
+	return resp
+}
@@ -0,0 +1,159 @@
+package client
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// cacheServer отвечает телом с номером запроса, ETag зависит только от пути; на If-None-Match с тем же ETag — 304
+type cacheServer struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	requests    int
+	conditional []string // If-None-Match каждого запроса
+}
+
+func newCacheServer(t *testing.T) *cacheServer {
+	s := &cacheServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests++
+		n := s.requests
+		s.conditional = append(s.conditional, r.Header.Get("If-None-Match"))
+		s.mu.Unlock()
+
+		etag := `"` + r.URL.Path + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Set-Cookie", "sid=secret")
+		io.WriteString(w, r.URL.Path+" #"+strconv.Itoa(n)+strings.Repeat(".", 200))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *cacheServer) get(t *testing.T, tr Transport, path string) (string, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, s.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := tr.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("%s: статус %d", path, resp.StatusCode)
+	}
+	return strings.TrimRight(string(body), "."), resp.Header.Get("X-Cache")
+}
+
+func newTestCache(cfg CacheConfig) *CacheTransport {
+	return &CacheTransport{Base: TransportFunc(http.DefaultTransport.RoundTrip), cfg: cfg}
+}
+
+func TestCacheTTLAndRevalidation(t *testing.T) {
+	srv := newCacheServer(t)
+	dir := t.TempDir()
+	ct := newTestCache(CacheConfig{Dir: dir, TTL: 50 * time.Millisecond})
+
+	tests := []struct {
+		wait     time.Duration
+		body     string
+		xCache   string
+		requests int
+		inm      string // If-None-Match последнего запроса к серверу
+	}{
+		{0, "/api/stores/960 #1", "", 1, ""},
+		// в пределах TTL — без запроса к серверу
+		{0, "/api/stores/960 #1", "HIT", 1, ""},
+		// TTL истёк — условный запрос, 304 отдаёт тело из кэша и продлевает запись
+		{80 * time.Millisecond, "/api/stores/960 #1", "REVALIDATED", 2, `"/api/stores/960"`},
+		{0, "/api/stores/960 #1", "HIT", 2, `"/api/stores/960"`},
+	}
+	for i, tt := range tests {
+		time.Sleep(tt.wait)
+		body, xCache := srv.get(t, ct, "/api/stores/960")
+		if body != tt.body || xCache != tt.xCache {
+			t.Errorf("запрос %d: %q X-Cache=%q, ожидалось %q X-Cache=%q", i+1, body, xCache, tt.body, tt.xCache)
+		}
+		if srv.requests != tt.requests || srv.conditional[len(srv.conditional)-1] != tt.inm {
+			t.Errorf("запрос %d: к серверу %d запросов, If-None-Match=%q, ожидалось %d, %q",
+				i+1, srv.requests, srv.conditional[len(srv.conditional)-1], tt.requests, tt.inm)
+		}
+	}
+
+	// Set-Cookie не сохраняется на диске
+	files, _ := os.ReadDir(dir)
+	for _, f := range files {
+		b, _ := os.ReadFile(filepath.Join(dir, f.Name()))
+		if strings.Contains(string(b), "sid=secret") {
+			t.Errorf("cookie в файле кэша %s", f.Name())
+		}
+	}
+}
+
+func TestCacheSkipsNonGET(t *testing.T) {
+	srv := newCacheServer(t)
+	ct := newTestCache(CacheConfig{Dir: t.TempDir(), TTL: time.Hour})
+
+	for range 2 {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/cart", nil)
+		resp, err := ct.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if srv.requests != 2 {
+		t.Errorf("POST: к серверу %d запросов, ожидалось 2", srv.requests)
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	srv := newCacheServer(t)
+	dir := t.TempDir()
+	// запись — около 500 байт, в лимит помещаются две
+	ct := newTestCache(CacheConfig{Dir: dir, TTL: time.Hour, MaxBytes: 1200})
+
+	for _, p := range []string{"/a", "/b", "/c"} {
+		srv.get(t, ct, p)
+		time.Sleep(10 * time.Millisecond) // разное время изменения файлов
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var total int64
+	for _, f := range files {
+		info, _ := f.Info()
+		total += info.Size()
+	}
+	if len(files) != 2 || total > 1200 {
+		t.Fatalf("в кэше %d файлов, %d байт, ожидалось 2 файла не больше 1200 байт", len(files), total)
+	}
+
+	// вытеснена самая старая запись
+	before := srv.requests
+	if _, xCache := srv.get(t, ct, "/c"); xCache != "HIT" {
+		t.Errorf("/c: X-Cache=%q, ожидалось HIT", xCache)
+	}
+	if _, xCache := srv.get(t, ct, "/a"); xCache != "" || srv.requests != before+1 {
+		t.Errorf("/a: X-Cache=%q, запросов к серверу %d, ожидался запрос к серверу", xCache, srv.requests-before)
+	}
+}
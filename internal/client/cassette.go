@@ -0,0 +1,220 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+type CassetteMode string
+
+const (
+	CassetteOff    CassetteMode = "off"
+	CassetteRecord CassetteMode = "record" // реальные запросы + запись ответов в файл
+	CassetteReplay CassetteMode = "replay" // ответы только из файла, сеть не используется
+)
+
+type CassetteConfig struct {
+	Mode CassetteMode
+	Path string
+}
+
+func (c CassetteConfig) Enabled() bool {
+	return c.Mode == CassetteRecord || c.Mode == CassetteReplay
+}
+
+const redacted = "REDACTED"
+
+// заголовки и query-параметры, значения которых не попадают в кассету
+var (
+	redactHeaders = []string{"Cookie", "Set-Cookie", "Authorization", "Proxy-Authorization"}
+	redactParams  = []string{"token", "access_token", "api_key", "key", "sid"}
+)
+
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+}
+
+type RecordedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body"`
+}
+
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// Cassette набор записанных пар запрос/ответ в JSON файле на диске
+type Cassette struct {
+	path string
+
+	mu           sync.Mutex
+	Interactions []Interaction `json:"interactions"`
+	used         []bool
+}
+
+// LoadCassette читает кассету; отсутствующий файл — пустая кассета
+func LoadCassette(path string) (*Cassette, error) {
+	c := &Cassette{path: path}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("кассета %s: %w", path, err)
+	}
+	c.used = make([]bool, len(c.Interactions))
+	return c, nil
+}
+
+func (c *Cassette) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.saveLocked()
+}
+
+func (c *Cassette) saveLocked() error {
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, b, 0o644)
+}
+
+func (c *Cassette) add(it Interaction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Interactions = append(c.Interactions, it)
+	c.used = append(c.used, true)
+	return c.saveLocked()
+}
+
+// match ищет ответ по method + path + query; одинаковые запросы отдаются по порядку записи,
+// после исчерпания повторяется последний
+func (c *Cassette) match(req *http.Request) (Interaction, bool) {
+	key := matchKey(req.Method, redactURL(req.URL))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	last := -1
+	for i, it := range c.Interactions {
+		u, err := url.Parse(it.Request.URL)
+		if err != nil || matchKey(it.Request.Method, u) != key {
+			continue
+		}
+		if !c.used[i] {
+			c.used[i] = true
+			return it, true
+		}
+		last = i
+	}
+	if last >= 0 {
+		return c.Interactions[last], true
+	}
+	return Interaction{}, false
+}
+
+func matchKey(method string, u *url.URL) string {
+	// url.Values.Encode сортирует параметры, порядок в запросе не важен
+	return strings.ToUpper(method) + " " + u.Path + "?" + u.Query().Encode()
+}
+
+// RecordTransport пропускает запросы в Base и записывает ответы в кассету
+type RecordTransport struct {
+	Base     Transport
+	Cassette *Cassette
+}
+
+func (t *RecordTransport) Do(req *http.Request) (*http.Response, error) {
+	resp, err := t.Base.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	it := Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    redactURL(req.URL).String(),
+			Header: redactHeader(req.Header),
+		},
+		Response: RecordedResponse{
+			Status: resp.StatusCode,
+			Header: redactHeader(resp.Header),
+			Body:   string(body),
+		},
+	}
+	if err := t.Cassette.add(it); err != nil {
+		return nil, fmt.Errorf("запись кассеты: %w", err)
+	}
+
+	return resp, nil
+}
+
+// ReplayTransport отдаёт ответы из кассеты без обращения к сети
+type ReplayTransport struct {
+	Cassette *Cassette
+}
+
+func (t *ReplayTransport) Do(req *http.Request) (*http.Response, error) {
+	it, ok := t.Cassette.match(req)
+	if !ok {
+		return nil, fmt.Errorf("кассета %s: нет записи для %s %s", t.Cassette.path, req.Method, redactURL(req.URL).String())
+	}
+
+	return newResponse(req, it.Response.Status, it.Response.Header, it.Response.Body), nil
+}
+
+func redactHeader(h http.Header) http.Header {
+	out := h.Clone()
+	for _, name := range redactHeaders {
+		if _, ok := out[name]; ok {
+			out[name] = []string{redacted}
+		}
+	}
+	return out
+}
+
+func redactURL(u *url.URL) *url.URL {
+	cp := *u
+	cp.User = nil
+
+	q := cp.Query()
+	changed := false
+	for _, p := range redactParams {
+		if q.Has(p) {
+			q.Set(p, redacted)
+			changed = true
+		}
+	}
+	if changed {
+		cp.RawQuery = q.Encode()
+	}
+	return &cp
+}
@@ -0,0 +1,155 @@
+package client
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCassetteRecordReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "kuper.json")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: "secret-session"})
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"path":"`+r.URL.Path+`","page":"`+r.URL.Query().Get("page")+`"}`)
+	}))
+	defer srv.Close()
+
+	cas, err := LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := &RecordTransport{Base: TransportFunc(http.DefaultTransport.RoundTrip), Cassette: cas}
+	for _, q := range []string{"?page=1&per_page=5&token=abc", "?page=2&per_page=5&token=abc"} {
+		req := httptest.NewRequest(http.MethodGet, srv.URL+"/api/v3/stores/960/products"+q, nil)
+		req.RequestURI = ""
+		req.Header.Set("Cookie", "sid=secret-session")
+		req.Header.Set("Authorization", "Bearer secret-token")
+		req.Header.Set("Accept", "application/json")
+		resp, err := rec.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	// секреты не попадают в файл кассеты
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"secret-session", "secret-token", "token=abc"} {
+		if strings.Contains(string(raw), secret) {
+			t.Errorf("в кассете остался секрет %q", secret)
+		}
+	}
+	var saved Cassette
+	if err := json.Unmarshal(raw, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.Interactions) != 2 {
+		t.Fatalf("записано %d запросов, ожидалось 2", len(saved.Interactions))
+	}
+	it := saved.Interactions[0]
+	if it.Request.Header.Get("Cookie") != redacted || it.Request.Header.Get("Authorization") != redacted ||
+		it.Response.Header.Get("Set-Cookie") != redacted {
+		t.Errorf("заголовки не скрыты: %v / %v", it.Request.Header, it.Response.Header)
+	}
+	if it.Request.Header.Get("Accept") != "application/json" {
+		t.Errorf("обычный заголовок потерян: %v", it.Request.Header)
+	}
+	if !strings.Contains(it.Request.URL, "token="+redacted) {
+		t.Errorf("token в URL не скрыт: %s", it.Request.URL)
+	}
+
+	// воспроизведение: другой хост, другой порядок параметров
+	cas, err = LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay := &ReplayTransport{Cassette: cas}
+	for _, tt := range []struct{ url, page string }{
+		{"https://kuper.ru/api/v3/stores/960/products?per_page=5&page=2&token=other", "2"},
+		{"https://kuper.ru/api/v3/stores/960/products?token=abc&page=1&per_page=5", "1"},
+	} {
+		resp, err := replay.Do(httptest.NewRequest(http.MethodGet, tt.url, nil))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.url, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), `"page":"`+tt.page+`"`) {
+			t.Errorf("%s: ответ %d %s, ожидалась страница %s", tt.url, resp.StatusCode, body, tt.page)
+		}
+	}
+}
+
+func TestCassetteMatch(t *testing.T) {
+	cas := &Cassette{path: "kuper.json"}
+	for i, body := range []string{"первый", "второй"} {
+		cas.Interactions = append(cas.Interactions, Interaction{
+			Request:  RecordedRequest{Method: http.MethodGet, URL: "https://kuper.ru/api/stores/960?a=1&b=2"},
+			Response: RecordedResponse{Status: 200 + i, Body: body},
+		})
+	}
+	cas.Interactions = append(cas.Interactions, Interaction{
+		Request:  RecordedRequest{Method: http.MethodPost, URL: "https://kuper.ru/api/stores/960?a=1&b=2"},
+		Response: RecordedResponse{Status: 201, Body: "post"},
+	})
+	cas.used = make([]bool, len(cas.Interactions))
+	replay := &ReplayTransport{Cassette: cas}
+
+	tests := []struct {
+		method, url string
+		status      int
+		body        string
+	}{
+		// одинаковые запросы — по порядку записи, затем повторяется последний
+		{http.MethodGet, "http://localhost:8081/api/stores/960?b=2&a=1", 200, "первый"},
+		{http.MethodGet, "https://kuper.ru/api/stores/960?a=1&b=2", 201, "второй"},
+		{http.MethodGet, "https://kuper.ru/api/stores/960?a=1&b=2", 201, "второй"},
+		{http.MethodPost, "https://kuper.ru/api/stores/960?a=1&b=2", 201, "post"},
+	}
+	for _, tt := range tests {
+		resp, err := replay.Do(httptest.NewRequest(tt.method, tt.url, nil))
+		if err != nil {
+			t.Fatalf("%s %s: %v", tt.method, tt.url, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != tt.status || string(body) != tt.body {
+			t.Errorf("%s %s = %d %q, ожидалось %d %q", tt.method, tt.url, resp.StatusCode, body, tt.status, tt.body)
+		}
+	}
+
+	// промах: другой путь, другой query, другой метод
+	for _, miss := range []struct{ method, url string }{
+		{http.MethodGet, "https://kuper.ru/api/stores/961?a=1&b=2"},
+		{http.MethodGet, "https://kuper.ru/api/stores/960?a=1"},
+		{http.MethodDelete, "https://kuper.ru/api/stores/960?a=1&b=2"},
+	} {
+		resp, err := replay.Do(httptest.NewRequest(miss.method, miss.url, nil))
+		if err == nil {
+			resp.Body.Close()
+			t.Errorf("%s %s: ожидался промах кассеты", miss.method, miss.url)
+		} else if !strings.Contains(err.Error(), "нет записи") {
+			t.Errorf("%s %s: ошибка %v", miss.method, miss.url, err)
+		}
+	}
+}
+
+func TestLoadCassetteMissing(t *testing.T) {
+	cas, err := LoadCassette(filepath.Join(t.TempDir(), "нет.json"))
+	if err != nil || len(cas.Interactions) != 0 {
+		t.Errorf("LoadCassette отсутствующего файла = %v, %v, ожидалась пустая кассета", cas, err)
+	}
+	bad := filepath.Join(t.TempDir(), "bad.json")
+	os.WriteFile(bad, []byte("{"), 0o644)
+	if _, err := LoadCassette(bad); err == nil {
+		t.Error("LoadCassette битого файла: ожидалась ошибка")
+	}
+}
@@ -0,0 +1,183 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ChaosConfig внесение сбоев для проверки ретраев, ротации прокси и обработки ошибок без сети.
+// Rate — вероятность от 0 до 1 на каждый запрос; из сбоев (ошибка, 429, 5xx, капча, обрыв тела)
+// на запрос выпадает не больше одного, поэтому сумма их вероятностей не должна превышать 1
+type ChaosConfig struct {
+	LatencyRate float64
+	Latency     time.Duration // задержка выбирается случайно от 0 до Latency
+
+	ErrorRate       float64 // ошибка соединения вместо ответа
+	RateLimitRate   float64 // 429 с Retry-After
+	RetryAfter      time.Duration
+	ServerErrorRate float64 // 500/502/503/504
+	ChallengeRate   float64 // html-страница капчи вместо json
+	TruncateRate    float64 // настоящий ответ с оборванным телом
+
+	Seed int64 // 0 — случайная последовательность сбоев
+}
+
+func (c ChaosConfig) Enabled() bool {
+	return (c.LatencyRate > 0 && c.Latency > 0) || c.faultRate() > 0
+}
+
+func (c ChaosConfig) faultRate() float64 {
+	return c.ErrorRate + c.RateLimitRate + c.ServerErrorRate + c.ChallengeRate + c.TruncateRate
+}
+
+// Validate проверяет, что вероятности в допустимых пределах
+func (c ChaosConfig) Validate() error {
+	rates := map[string]float64{
+		"latency_rate":      c.LatencyRate,
+		"error_rate":        c.ErrorRate,
+		"rate_limit_rate":   c.RateLimitRate,
+		"server_error_rate": c.ServerErrorRate,
+		"challenge_rate":    c.ChallengeRate,
+		"truncate_rate":     c.TruncateRate,
+	}
+	for name, r := range rates {
+		if r < 0 || r > 1 {
+			return fmt.Errorf("%s=%v вне диапазона [0, 1]", name, r)
+		}
+	}
+	if sum := c.faultRate(); sum > 1 {
+		return fmt.Errorf("сумма вероятностей сбоев %v больше 1", sum)
+	}
+	return nil
+}
+
+// ErrChaos ошибка соединения, внесённая ChaosTransport
+var ErrChaos = errors.New("chaos: обрыв соединения")
+
+const chaosChallengePage = `<!DOCTYPE html>
+<html><head><title>Проверка браузера</title></head>
+<body><div id="captcha">Подтвердите, что вы не робот</div></body></html>`
+
+// ChaosTransport вносит задержки и сбои перед обращением к Base
+type ChaosTransport struct {
+	Base Transport
+	Cfg  ChaosConfig
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+func NewChaosTransport(base Transport, cfg ChaosConfig) *ChaosTransport {
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &ChaosTransport{Base: base, Cfg: cfg, rnd: rand.New(rand.NewSource(seed))}
+}
+
+func (t *ChaosTransport) float() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.rnd.Float64()
+}
+
+func (t *ChaosTransport) Do(req *http.Request) (*http.Response, error) {
+	if t.Cfg.LatencyRate > 0 && t.float() < t.Cfg.LatencyRate {
+		d := time.Duration(t.float() * float64(t.Cfg.Latency))
+		if err := sleepCtx(req.Context(), d); err != nil {
+			return nil, err
+		}
+	}
+
+	r := t.float()
+	if r -= t.Cfg.ErrorRate; r < 0 {
+		return nil, &net.OpError{Op: "read", Net: "tcp", Addr: chaosAddr(req.URL), Err: ErrChaos}
+	}
+	if r -= t.Cfg.RateLimitRate; r < 0 {
+		h := http.Header{"Content-Type": {"application/json"}}
+		if t.Cfg.RetryAfter > 0 {
+			h.Set("Retry-After", strconv.Itoa(int(t.Cfg.RetryAfter.Seconds())))
+		}
+		return newResponse(req, http.StatusTooManyRequests, h, `{"code":"too_many_requests","message":"chaos"}`), nil
+	}
+	if r -= t.Cfg.ServerErrorRate; r < 0 {
+		statuses := []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+		status := statuses[int(t.float()*float64(len(statuses)))%len(statuses)]
+		return newResponse(req, status, http.Header{"Content-Type": {"text/plain"}}, http.StatusText(status)), nil
+	}
+	if r -= t.Cfg.ChallengeRate; r < 0 {
+		return newResponse(req, http.StatusForbidden, http.Header{"Content-Type": {"text/html; charset=utf-8"}}, chaosChallengePage), nil
+	}
+
+	resp, err := t.Base.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if r -= t.Cfg.TruncateRate; r < 0 {
+		resp.Body = &truncatedBody{rc: resp.Body, left: 64 + int(t.float()*512)}
+		resp.ContentLength = -1
+	}
+	return resp, nil
+}
+
+// truncatedBody отдаёт left байт и затем io.ErrUnexpectedEOF, как оборванное соединение
+type truncatedBody struct {
+	rc   io.ReadCloser
+	left int
+}
+
+func (b *truncatedBody) Read(p []byte) (int, error) {
+	if b.left <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if len(p) > b.left {
+		p = p[:b.left]
+	}
+	n, err := b.rc.Read(p)
+	b.left -= n
+	if err == io.EOF && b.left > 0 {
+		// тело оказалось короче точки обрыва — отдаём как есть
+		return n, io.EOF
+	}
+	return n, err
+}
+
+func (b *truncatedBody) Close() error { return b.rc.Close() }
+
+type chaosAddrT string
+
+func (a chaosAddrT) Network() string { return "tcp" }
+func (a chaosAddrT) String() string  { return string(a) }
+
+func chaosAddr(u *url.URL) net.Addr { return chaosAddrT(u.Host) }
+
+// ProxyChaos сбои отдельно на каждом прокси: проверяет ротацию и breaker'ы прокси.
+// С заданным Seed у каждого прокси своя, но воспроизводимая последовательность сбоев
+func ProxyChaos(cfg ChaosConfig) ProxyLayer {
+	return func(proxy *url.URL, base Transport) Transport {
+		c := cfg
+		if c.Seed != 0 {
+			c.Seed = proxySeed(c.Seed, proxy.Host)
+		}
+		return NewChaosTransport(base, c)
+	}
+}
+
+// proxySeed seed прокси из общего seed и адреса прокси
+func proxySeed(seed int64, host string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(host))
+	if s := seed ^ int64(h.Sum64()); s != 0 {
+		return s
+	}
+	return seed
+}
@@ -0,0 +1,183 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// CookieStore выдаёт cookie jar на каждую сессию (прокси) и сохраняет их на диск между запусками
+type CookieStore struct {
+	dir string // пусто — cookie только в памяти
+
+	mu   sync.Mutex
+	jars map[string]*persistentJar
+	seed []seedCookies
+}
+
+type seedCookies struct {
+	u       *url.URL
+	cookies []*http.Cookie
+}
+
+func NewCookieStore(dir string) *CookieStore {
+	return &CookieStore{dir: dir, jars: make(map[string]*persistentJar)}
+}
+
+// Seed cookie, которые выставляются каждой сессии для адреса u (например, выбранный магазин/регион)
+func (s *CookieStore) Seed(u *url.URL, values map[string]string) {
+	if len(values) == 0 {
+		return
+	}
+	cookies := make([]*http.Cookie, 0, len(values))
+	for name, v := range values {
+		cookies = append(cookies, &http.Cookie{Name: name, Value: v, Path: "/"})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seed = append(s.seed, seedCookies{u: u, cookies: cookies})
+}
+
+// Jar cookie jar сессии; key — адрес прокси или "direct"
+func (s *CookieStore) Jar(key string) (http.CookieJar, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if j, ok := s.jars[key]; ok {
+		return j, nil
+	}
+
+	inner, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	j := &persistentJar{jar: inner, entries: make(map[string]storedCookie)}
+
+	if s.dir != "" {
+		if err := j.load(s.pathFor(key)); err != nil {
+			return nil, err
+		}
+	}
+	for _, sd := range s.seed {
+		j.SetCookies(sd.u, sd.cookies)
+	}
+
+	s.jars[key] = j
+	return j, nil
+}
+
+// Save записывает все jar'ы на диск
+func (s *CookieStore) Save() error {
+	if s.dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	for key, j := range s.jars {
+		if err := j.save(s.pathFor(key)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// pathFor имя файла — хэш ключа, чтобы адрес прокси с паролем не попадал в имена файлов
+func (s *CookieStore) pathFor(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:8])+".json")
+}
+
+type storedCookie struct {
+	URL    string       `json:"url"`
+	Cookie *http.Cookie `json:"cookie"`
+}
+
+// persistentJar cookiejar.Jar не умеет отдавать все cookie, поэтому выставленные cookie дублируются в entries
+type persistentJar struct {
+	jar *cookiejar.Jar
+
+	mu      sync.Mutex
+	entries map[string]storedCookie
+}
+
+func (j *persistentJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+
+	origin := (&url.URL{Scheme: u.Scheme, Host: u.Host}).String()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, c := range cookies {
+		key := c.Domain + "|" + c.Path + "|" + c.Name + "|" + u.Host
+		if c.MaxAge < 0 || (!c.Expires.IsZero() && c.Expires.Before(time.Now())) {
+			delete(j.entries, key)
+			continue
+		}
+		cp := *c
+		if cp.MaxAge > 0 && cp.Expires.IsZero() {
+			cp.Expires = time.Now().Add(time.Duration(cp.MaxAge) * time.Second)
+			cp.MaxAge = 0
+		}
+		j.entries[key] = storedCookie{URL: origin, Cookie: &cp}
+	}
+}
+
+func (j *persistentJar) Cookies(u *url.URL) []*http.Cookie { return j.jar.Cookies(u) }
+
+func (j *persistentJar) load(path string) error {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var stored []storedCookie
+	if err := json.Unmarshal(b, &stored); err != nil {
+		return fmt.Errorf("cookies %s: %w", path, err)
+	}
+	for _, sc := range stored {
+		u, err := url.Parse(sc.URL)
+		if err != nil || sc.Cookie == nil {
+			continue
+		}
+		j.SetCookies(u, []*http.Cookie{sc.Cookie})
+	}
+	return nil
+}
+
+func (j *persistentJar) save(path string) error {
+	j.mu.Lock()
+	now := time.Now()
+	stored := make([]storedCookie, 0, len(j.entries))
+	for _, sc := range j.entries {
+		if !sc.Cookie.Expires.IsZero() && sc.Cookie.Expires.Before(now) {
+			continue
+		}
+		stored = append(stored, sc)
+	}
+	j.mu.Unlock()
+
+	b, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o600)
+}
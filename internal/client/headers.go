@@ -0,0 +1,172 @@
+package client
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// HeaderProfile согласованный набор заголовков одного браузера. Порядок заголовков профиль не задаёт:
+// net/http пишет заголовки HTTP/1.1 отсортированными по имени, а HTTP/2 кодирует их сам
+type HeaderProfile struct {
+	Name            string            `yaml:"name"`
+	UserAgent       string            `yaml:"user_agent"`
+	SecCHUA         string            `yaml:"sec_ch_ua"`          // пусто — браузер не шлёт client hints (Firefox, Safari)
+	SecCHUAMobile   string            `yaml:"sec_ch_ua_mobile"`   // ?0 / ?1
+	SecCHUAPlatform string            `yaml:"sec_ch_ua_platform"` // "Windows", "macOS", ...
+	AcceptLanguage  string            `yaml:"accept_language"`
+	Headers         map[string]string `yaml:"headers"` // любые дополнительные заголовки
+}
+
+// Apply перезаписывает заголовки браузера в запросе; client hints удаляются, если профиль их не задаёт
+func (p HeaderProfile) Apply(h http.Header) {
+	if p.UserAgent != "" {
+		h.Set("User-Agent", p.UserAgent)
+	}
+	if p.AcceptLanguage != "" {
+		h.Set("Accept-Language", p.AcceptLanguage)
+	}
+
+	if p.SecCHUA == "" {
+		h.Del("Sec-Ch-Ua")
+		h.Del("Sec-Ch-Ua-Mobile")
+		h.Del("Sec-Ch-Ua-Platform")
+	} else {
+		h.Set("Sec-Ch-Ua", p.SecCHUA)
+		h.Set("Sec-Ch-Ua-Mobile", p.SecCHUAMobile)
+		h.Set("Sec-Ch-Ua-Platform", p.SecCHUAPlatform)
+	}
+
+	for k, v := range p.Headers {
+		h.Set(k, v)
+	}
+}
+
+// DefaultHeaderProfiles встроенные профили актуальных десктопных браузеров
+func DefaultHeaderProfiles() []HeaderProfile {
+	return []HeaderProfile{
+		{
+			Name:            "chrome-windows",
+			UserAgent:       "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/144.0.0.0 Safari/537.36",
+			SecCHUA:         `"Not(A:Brand";v="8", "Chromium";v="144", "Google Chrome";v="144"`,
+			SecCHUAMobile:   "?0",
+			SecCHUAPlatform: `"Windows"`,
+			AcceptLanguage:  "ru-RU,ru;q=0.9,en-US;q=0.8,en;q=0.7",
+		},
+		{
+			Name:            "chrome-macos",
+			UserAgent:       "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/143.0.0.0 Safari/537.36",
+			SecCHUA:         `"Google Chrome";v="143", "Chromium";v="143", "Not A(Brand";v="24"`,
+			SecCHUAMobile:   "?0",
+			SecCHUAPlatform: `"macOS"`,
+			AcceptLanguage:  "ru,en;q=0.9",
+		},
+		{
+			Name:            "edge-windows",
+			UserAgent:       "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/144.0.0.0 Safari/537.36 Edg/144.0.0.0",
+			SecCHUA:         `"Not(A:Brand";v="8", "Chromium";v="144", "Microsoft Edge";v="144"`,
+			SecCHUAMobile:   "?0",
+			SecCHUAPlatform: `"Windows"`,
+			AcceptLanguage:  "ru,en;q=0.9,en-GB;q=0.8,en-US;q=0.7",
+		},
+		{
+			Name:           "firefox-windows",
+			UserAgent:      "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:146.0) Gecko/20100101 Firefox/146.0",
+			AcceptLanguage: "ru-RU,ru;q=0.8,en-US;q=0.5,en;q=0.3",
+			Headers: map[string]string{
+				"Accept": "application/json, text/plain, */*",
+			},
+		},
+		{
+			Name:            "yandex-windows",
+			UserAgent:       "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/142.0.0.0 YaBrowser/25.12.0.0 Safari/537.36",
+			SecCHUA:         `"Chromium";v="142", "YaBrowser";v="25.12", "Not_A Brand";v="99", "Yowser";v="2.5"`,
+			SecCHUAMobile:   "?0",
+			SecCHUAPlatform: `"Windows"`,
+			AcceptLanguage:  "ru,en;q=0.9",
+		},
+	}
+}
+
+// LoadHeaderProfiles читает профили из yaml файла вида `profiles: [...]`
+func LoadHeaderProfiles(path string) ([]HeaderProfile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f struct {
+		Profiles []HeaderProfile `yaml:"profiles"`
+	}
+	if err := yaml.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("профили заголовков %s: %w", path, err)
+	}
+	if len(f.Profiles) == 0 {
+		return nil, fmt.Errorf("профили заголовков %s: список profiles пуст", path)
+	}
+	for i, p := range f.Profiles {
+		if p.UserAgent == "" {
+			return nil, fmt.Errorf("профили заголовков %s: у профиля #%d (%s) не задан user_agent", path, i+1, p.Name)
+		}
+	}
+	return f.Profiles, nil
+}
+
+// HeaderProfileSet закрепляет за каждой сессией (прокси) свой профиль на всё время работы
+type HeaderProfileSet struct {
+	profiles []HeaderProfile
+	pinned   *HeaderProfile
+}
+
+// NewHeaderProfileSet pin — имя профиля для всех сессий; пусто — профиль выбирается по ключу сессии
+func NewHeaderProfileSet(profiles []HeaderProfile, pin string) (*HeaderProfileSet, error) {
+	if len(profiles) == 0 {
+		profiles = DefaultHeaderProfiles()
+	}
+	s := &HeaderProfileSet{profiles: profiles}
+
+	if pin != "" {
+		for i := range profiles {
+			if profiles[i].Name == pin {
+				s.pinned = &profiles[i]
+				return s, nil
+			}
+		}
+		return nil, fmt.Errorf("профиль заголовков %q не найден", pin)
+	}
+	return s, nil
+}
+
+// ForSession профиль сессии по хэшу ключа: не зависит от порядка первых запросов воркеров,
+// поэтому при неизменном списке профилей прокси между запусками выглядит одним и тем же браузером
+func (s *HeaderProfileSet) ForSession(key string) HeaderProfile {
+	if s.pinned != nil {
+		return *s.pinned
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return s.profiles[h.Sum32()%uint32(len(s.profiles))]
+}
+
+// ProfileTransport подставляет заголовки профиля в каждый запрос
+type ProfileTransport struct {
+	Base    Transport
+	Profile HeaderProfile
+}
+
+func (t *ProfileTransport) Do(req *http.Request) (*http.Response, error) {
+	req2 := req.Clone(req.Context())
+	t.Profile.Apply(req2.Header)
+	return t.Base.Do(req2)
+}
+
+// ProxyHeaderProfiles свой профиль браузера на каждый прокси
+func ProxyHeaderProfiles(set *HeaderProfileSet) ProxyLayer {
+	return func(proxy *url.URL, base Transport) Transport {
+		return &ProfileTransport{Base: base, Profile: set.ForSession(proxy.Host)}
+	}
+}
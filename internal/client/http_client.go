@@ -1,14 +1,17 @@
 package client
 
 import (
-	"log"
+	"log/slog"
 	"net"
 	"net/http"
 	"net/http/cookiejar"
 	"time"
+
+	"kuperparser/internal/logging"
 )
 
-func NewHTTPClient(timeout time.Duration) *http.Client {
+// NewHTTPClient базовый клиент; logger может быть nil (используется slog.Default)
+func NewHTTPClient(timeout time.Duration, logger *slog.Logger) *http.Client {
 	jar, _ := cookiejar.New(nil)
 
 	tr := &http.Transport{
@@ -29,26 +32,35 @@ func NewHTTPClient(timeout time.Duration) *http.Client {
 		ForceAttemptHTTP2: true,
 	}
 
-	return &http.Client{
+	c := &http.Client{
 		Transport: tr,
 		Timeout:   timeout,
 		Jar:       jar,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			prev := via[len(via)-1]
-			log.Printf("[REDIRECT] %s -> %s (hops=%d)", prev.URL.String(), req.URL.String(), len(via))
-
-			if jar != nil {
-				ck := jar.Cookies(req.URL)
-				names := make([]string, 0, len(ck))
-				for _, c := range ck {
-					names = append(names, c.Name)
-				}
-				log.Printf("[COOKIES] for %s: %v", req.URL.Host, names)
+	}
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		prev := via[len(via)-1]
+		l := logging.FromContext(req.Context(), logger)
+		l.Info("редирект", "from", RedactURL(prev.URL), "url", RedactURL(req.URL), "hops", len(via))
+
+		// редирект на капчу — дальше идти бессмысленно
+		if reason, ok := BlockedURL(req.URL); ok {
+			l.Warn("редирект на страницу антибота", "url", RedactURL(req.URL))
+			return &BlockedError{URL: RedactURL(prev.URL), Reason: reason}
+		}
+
+		// cookie из jar базового клиента: jar'ы прокси-клиентов здесь не видны
+		if c.Jar != nil && l.Enabled(req.Context(), slog.LevelDebug) {
+			ck := c.Jar.Cookies(req.URL)
+			names := make([]string, 0, len(ck))
+			for _, cookie := range ck {
+				names = append(names, cookie.Name)
 			}
-			return nil
-		},
+			l.Debug("cookie при редиректе", "url", RedactURL(req.URL), "cookies", names)
+		}
+		return nil
 	}
 
+	return c
 }
 
 type HTTPTransport struct {
@@ -1,6 +1,9 @@
 package client
 
-import "net/http"
+import (
+	"net/http"
+	"time"
+)
 
 type Limiter struct {
 	sem chan struct{}
@@ -19,10 +22,15 @@ func (l *Limiter) Release() { <-l.sem }
 type LimitedTransport struct {
 	Base    Transport
 	Limiter *Limiter
+	Metrics *Metrics // может быть nil
 }
 
 func (t *LimitedTransport) Do(req *http.Request) (*http.Response, error) {
+	start := time.Now()
 	t.Limiter.Acquire()
+	if t.Metrics != nil {
+		t.Metrics.LimiterWait.Observe(time.Since(start).Seconds())
+	}
 	defer t.Limiter.Release()
 	return t.Base.Do(req)
 }
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"kuperparser/internal/metrics"
+)
+
+// Metrics метрики транспортного слоя
+type Metrics struct {
+	Requests    *metrics.CounterVec   // запросы по endpoint/status/proxy
+	Latency     *metrics.HistogramVec // длительность запросов по endpoint/proxy
+	Retries     *metrics.CounterVec   // повторы по endpoint/reason
+	LimiterWait *metrics.HistogramVec // ожидание свободного слота limiter'а
+}
+
+func NewMetrics(reg *metrics.Registry) *Metrics {
+	return &Metrics{
+		Requests: reg.NewCounterVec("kuper_http_requests_total",
+			"HTTP requests by endpoint, status and proxy.", "endpoint", "status", "proxy"),
+		Latency: reg.NewHistogramVec("kuper_http_request_duration_seconds",
+			"HTTP request latency by endpoint and proxy.", nil, "endpoint", "proxy"),
+		Retries: reg.NewCounterVec("kuper_http_retries_total",
+			"Retried HTTP attempts by endpoint and reason.", "endpoint", "reason"),
+		LimiterWait: reg.NewHistogramVec("kuper_limiter_wait_seconds",
+			"Time spent waiting for a concurrency limiter slot.", nil),
+	}
+}
+
+// ProxyFailures попытки через прокси, завершившиеся сетевой ошибкой
+func (m *Metrics) ProxyFailures() float64 {
+	if m == nil {
+		return 0
+	}
+	return m.Requests.SumWhere(func(l []string) bool {
+		// метки: endpoint, status, proxy
+		return l[1] == "error" && l[2] != "direct" && l[2] != "replay"
+	})
+}
+
+// RetriesTotal все повторы запросов
+func (m *Metrics) RetriesTotal() float64 {
+	if m == nil {
+		return 0
+	}
+	return m.Retries.Sum()
+}
+
+func (m *Metrics) observe(req *http.Request, proxy string, start time.Time, resp *http.Response, err error) {
+	endpoint := Endpoint(req.URL)
+	status := "error"
+	if err == nil && resp != nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	m.Requests.Inc(endpoint, status, proxy)
+	m.Latency.Observe(time.Since(start).Seconds(), endpoint, proxy)
+}
+
+func (m *Metrics) retry(req *http.Request, reason string) {
+	if m == nil {
+		return
+	}
+	m.Retries.Inc(Endpoint(req.URL), reason)
+}
+
+// MetricsTransport считает каждую попытку запроса
+type MetricsTransport struct {
+	Base    Transport
+	Metrics *Metrics
+	Proxy   string // метка proxy; для прямых запросов "direct"
+}
+
+func (t *MetricsTransport) Do(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.Base.Do(req)
+	t.Metrics.observe(req, t.Proxy, start, resp, err)
+	return resp, err
+}
+
+// ProxyMetrics метрики запросов с меткой конкретного прокси
+func ProxyMetrics(m *Metrics) ProxyLayer {
+	return func(proxy *url.URL, base Transport) Transport {
+		return &MetricsTransport{Base: base, Metrics: m, Proxy: proxy.Host}
+	}
+}
+
+// Endpoint нормализует путь для меток: числа -> {id}, slug отдела -> {slug}
+func Endpoint(u *url.URL) string {
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i, p := range parts {
+		if _, err := strconv.Atoi(p); err == nil {
+			parts[i] = "{id}"
+			continue
+		}
+		if i > 0 && parts[i-1] == "departments" {
+			parts[i] = "{slug}"
+		}
+	}
+	return "/" + strings.Join(parts, "/")
+}
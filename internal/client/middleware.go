@@ -0,0 +1,177 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Middleware слой поверх транспорта
+type Middleware func(Transport) Transport
+
+// MiddlewareFactory создаёт слой по конфигу; nil без ошибки — слой выключен в конфиге и пропускается
+type MiddlewareFactory func(cfg TransportConfig) (Middleware, error)
+
+// DefaultOrder порядок слоёв над базовым транспортом (снаружи внутрь)
+var DefaultOrder = []string{"limiter", "cache", "retry", "breaker", "ratelimit"}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]MiddlewareFactory{
+		"limiter":   limiterMiddleware,
+		"cache":     cacheMiddleware,
+		"retry":     retryMiddleware,
+		"breaker":   breakerMiddleware,
+		"ratelimit": rateLimitMiddleware,
+	}
+)
+
+// builtinEnabled включён ли встроенный слой в конфиге — без вызова фабрики и её побочных эффектов
+// (cache создаёт директорию); по нему проверяется, что включённый слой не пропущен в порядке слоёв
+var builtinEnabled = map[string]func(cfg TransportConfig) bool{
+	"limiter":   func(cfg TransportConfig) bool { return cfg.Workers > 0 },
+	"cache":     func(cfg TransportConfig) bool { return cfg.Cache.Enabled() },
+	"retry":     func(cfg TransportConfig) bool { return cfg.Retry.MaxRetries > 0 },
+	"breaker":   func(cfg TransportConfig) bool { return cfg.Breaker.Enabled() },
+	"ratelimit": func(cfg TransportConfig) bool { return cfg.RateLimit.Enabled() },
+}
+
+// RegisterMiddleware регистрирует слой, который можно указать по имени в порядке слоёв (http.middleware)
+func RegisterMiddleware(name string, f MiddlewareFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = f
+}
+
+// RegisteredMiddlewares имена всех зарегистрированных слоёв
+func RegisteredMiddlewares() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for n := range registry {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Chain применяет слои к base; первый в списке — самый внешний
+func Chain(base Transport, mws ...Middleware) Transport {
+	t := base
+	for i := len(mws) - 1; i >= 0; i-- {
+		if mws[i] != nil {
+			t = mws[i](t)
+		}
+	}
+	return t
+}
+
+// buildMiddlewares слои в порядке cfg.Order (или DefaultOrder); cfg.Middlewares имеют приоритет над реестром.
+// Встроенный слой, включённый в конфиге, и слой из cfg.Middlewares, которых нет в порядке, — ошибка:
+// иначе они молча не работали бы
+func buildMiddlewares(cfg TransportConfig) ([]Middleware, error) {
+	order := cfg.Order
+	if len(order) == 0 {
+		order = DefaultOrder
+	}
+
+	inOrder := make(map[string]bool, len(order))
+	for _, name := range order {
+		inOrder[name] = true
+	}
+	for name := range cfg.Middlewares {
+		if !inOrder[name] {
+			return nil, fmt.Errorf("слой %q передан, но не указан в порядке слоёв %v", name, order)
+		}
+	}
+	for _, name := range DefaultOrder {
+		if inOrder[name] {
+			continue
+		}
+		if enabled, ok := builtinEnabled[name]; ok && enabled(cfg) {
+			return nil, fmt.Errorf("слой %s включён в конфиге, но не указан в порядке слоёв %v (http.middleware)", name, order)
+		}
+	}
+
+	seen := make(map[string]bool, len(order))
+	out := make([]Middleware, 0, len(order))
+
+	for _, name := range order {
+		if seen[name] {
+			return nil, fmt.Errorf("слой %q указан дважды", name)
+		}
+		seen[name] = true
+
+		if mw, ok := cfg.Middlewares[name]; ok {
+			out = append(out, mw)
+			continue
+		}
+
+		registryMu.RLock()
+		f, ok := registry[name]
+		registryMu.RUnlock()
+		if !ok || f == nil {
+			return nil, fmt.Errorf("неизвестный слой %q (доступны: %v)", name, RegisteredMiddlewares())
+		}
+
+		mw, err := f(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("слой %s: %w", name, err)
+		}
+		out = append(out, mw)
+	}
+	return out, nil
+}
+
+func limiterMiddleware(cfg TransportConfig) (Middleware, error) {
+	if cfg.Workers <= 0 {
+		return nil, nil
+	}
+	l := NewLimiter(cfg.Workers)
+	return func(t Transport) Transport {
+		return &LimitedTransport{Base: t, Limiter: l, Metrics: cfg.Metrics}
+	}, nil
+}
+
+// cache под limiter'ом: попадание в кэш не проходит через ретраи и лимиты
+func cacheMiddleware(cfg TransportConfig) (Middleware, error) {
+	if !cfg.Cache.Enabled() {
+		return nil, nil
+	}
+	if err := os.MkdirAll(cfg.Cache.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("не удалось создать директорию кэша: %w", err)
+	}
+	return func(t Transport) Transport {
+		return &CacheTransport{Base: t, Logger: cfg.Logger, cfg: cfg.Cache}
+	}, nil
+}
+
+func retryMiddleware(cfg TransportConfig) (Middleware, error) {
+	if cfg.Retry.MaxRetries <= 0 {
+		return nil, nil
+	}
+	return func(t Transport) Transport {
+		rt := NewRetryTransport(t, cfg.Retry)
+		rt.Metrics = cfg.Metrics
+		rt.Logger = cfg.Logger
+		return rt
+	}, nil
+}
+
+// breaker: при разомкнутой цепи запрос не доходит до лимитов и прокси
+func breakerMiddleware(cfg TransportConfig) (Middleware, error) {
+	if !cfg.Breaker.Enabled() {
+		return nil, nil
+	}
+	return func(t Transport) Transport { return NewBreakerTransport(t, cfg.Breaker) }, nil
+}
+
+// ratelimit под ретраями: каждая попытка тоже проходит через лимит
+func rateLimitMiddleware(cfg TransportConfig) (Middleware, error) {
+	if !cfg.RateLimit.Enabled() {
+		return nil, nil
+	}
+	return func(t Transport) Transport { return NewRateLimitTransport(t, cfg.RateLimit) }, nil
+}
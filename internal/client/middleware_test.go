@@ -0,0 +1,47 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildMiddlewaresMissingLayer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	cfg := TransportConfig{
+		Order: []string{"limiter", "retry"},
+		Cache: CacheConfig{Dir: dir, TTL: time.Hour},
+	}
+
+	_, err := buildMiddlewares(cfg)
+	if err == nil || !strings.Contains(err.Error(), "слой cache включён") {
+		t.Fatalf("buildMiddlewares = %v, ожидалась ошибка о пропущенном cache", err)
+	}
+	// проверка порядка не вызывает фабрику: директория кэша не создаётся
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("директория кэша создана при проверке порядка слоёв: %v", err)
+	}
+
+	// выключенный слой можно не указывать
+	cfg.Cache = CacheConfig{}
+	if _, err := buildMiddlewares(cfg); err != nil {
+		t.Errorf("buildMiddlewares без cache: %v", err)
+	}
+}
+
+func TestBuildMiddlewaresUnknownLayer(t *testing.T) {
+	RegisterMiddleware("test-nil", nil)
+	t.Cleanup(func() {
+		registryMu.Lock()
+		delete(registry, "test-nil")
+		registryMu.Unlock()
+	})
+
+	for _, order := range [][]string{{"limiter", "nope"}, {"test-nil"}} {
+		if _, err := buildMiddlewares(TransportConfig{Order: order}); err == nil || !strings.Contains(err.Error(), "неизвестный слой") {
+			t.Errorf("порядок %v: %v, ожидалась ошибка о неизвестном слое", order, err)
+		}
+	}
+}
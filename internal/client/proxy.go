@@ -1,6 +1,7 @@
 package client
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/cookiejar"
@@ -19,13 +20,24 @@ func NewProxyRotator(raw []string) (*ProxyRotator, error) {
 	for _, s := range raw {
 		u, err := url.Parse(s)
 		if err != nil {
-			return nil, err
+			// url.Error содержит исходную строку вместе с паролем
+			var ue *url.Error
+			if errors.As(err, &ue) {
+				err = ue.Err
+			}
+			return nil, fmt.Errorf("некорректный адрес прокси %s: %v", redactRaw(s), err)
 		}
 		out = append(out, u)
 	}
 	return &ProxyRotator{proxies: out}, nil
 }
 
+func (r *ProxyRotator) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.proxies)
+}
+
 func (r *ProxyRotator) Next() *url.URL {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -37,12 +49,23 @@ func (r *ProxyRotator) Next() *url.URL {
 	return u
 }
 
+// ProxyLayer оборачивает транспорт отдельного прокси (лимиты, метрики и т.п. на уровне прокси)
+type ProxyLayer func(proxy *url.URL, base Transport) Transport
+
 type ProxyTransport struct {
 	baseClient *http.Client
 
-	rotator *ProxyRotator
-	mu      sync.Mutex
-	clients map[string]*http.Client
+	rotator    *ProxyRotator
+	mu         sync.Mutex
+	clients    map[string]*http.Client
+	transports map[string]Transport
+	layers     []ProxyLayer
+
+	// RotateOnBlock при ответе антибота сразу пробовать следующий прокси
+	RotateOnBlock bool
+
+	// Cookies хранилище cookie прокси-сессий; nil — свежий jar в памяти на каждый прокси
+	Cookies *CookieStore
 }
 
 func NewProxyTransportWithList(base *http.Client, proxyList []string) (*ProxyTransport, error) {
@@ -54,6 +77,7 @@ func NewProxyTransportWithList(base *http.Client, proxyList []string) (*ProxyTra
 		baseClient: base,
 		rotator:    rot,
 		clients:    make(map[string]*http.Client),
+		transports: make(map[string]Transport),
 	}, nil
 }
 
@@ -66,27 +90,74 @@ func NewProxyTransportWithRotation(base *http.Client, rotationURL string) (*Prox
 		baseClient: base,
 		rotator:    rot,
 		clients:    make(map[string]*http.Client),
+		transports: make(map[string]Transport),
 	}, nil
 }
 
 func (p *ProxyTransport) Do(req *http.Request) (*http.Response, error) {
-	proxyURL := p.rotator.Next()
-	if proxyURL == nil {
-		return p.baseClient.Do(req)
+	var lastErr error
+
+	// прокси с разомкнутым breaker'ом (или заблокированный антиботом) пропускаем и берём следующий
+	for i := 0; i < max(p.rotator.Len(), 1); i++ {
+		proxyURL := p.rotator.Next()
+		if proxyURL == nil {
+			return p.baseClient.Do(req)
+		}
+
+		t, err := p.transportForProxy(proxyURL)
+		if err != nil {
+			return nil, err
+		}
+
+		req2 := req.Clone(req.Context())
+
+		resp, doErr := t.Do(req2)
+		if doErr == nil {
+			return resp, nil
+		}
+		lastErr = ProxyError{Proxy: RedactURL(proxyURL), Err: doErr}
+		if !errors.Is(doErr, ErrCircuitOpen) && !(p.RotateOnBlock && errors.Is(doErr, ErrBlocked)) {
+			return nil, lastErr
+		}
 	}
+	return nil, lastErr
+}
+
+// Use добавляет слои, которые применяются к транспорту каждого прокси (первый — самый внешний)
+func (p *ProxyTransport) Use(layers ...ProxyLayer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.layers = append(p.layers, layers...)
+}
+
+func (p *ProxyTransport) transportForProxy(u *url.URL) (Transport, error) {
+	key := u.String()
 
-	cli, err := p.clientForProxy(proxyURL)
+	p.mu.Lock()
+	if t, ok := p.transports[key]; ok {
+		p.mu.Unlock()
+		return t, nil
+	}
+	p.mu.Unlock()
+
+	cli, err := p.clientForProxy(u)
 	if err != nil {
 		return nil, err
 	}
 
-	req2 := req.Clone(req.Context())
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if t, ok := p.transports[key]; ok {
+		return t, nil
+	}
 
-	resp, doErr := cli.Do(req2)
-	if doErr != nil {
-		return nil, ProxyError{Proxy: proxyURL.String(), Err: doErr}
+	var t Transport = &HTTPTransport{Client: cli}
+	for i := len(p.layers) - 1; i >= 0; i-- {
+		t = p.layers[i](u, t)
 	}
-	return resp, nil
+	p.transports[key] = t
+
+	return t, nil
 }
 
 func (p *ProxyTransport) clientForProxy(u *url.URL) (*http.Client, error) {
@@ -101,14 +172,23 @@ func (p *ProxyTransport) clientForProxy(u *url.URL) (*http.Client, error) {
 
 	baseTr, ok := p.baseClient.Transport.(*http.Transport)
 	if !ok || baseTr == nil {
-		return nil, fmt.Errorf("base http client transport must be *http.Transport")
+		return nil, errors.New("базовый http-клиент должен использовать *http.Transport")
 	}
 
 	tr := baseTr.Clone()
 	tr.Proxy = http.ProxyURL(u)
 
 	// отдельный cookie jar на каждый прокси
-	jar, _ := cookiejar.New(nil)
+	var jar http.CookieJar
+	if p.Cookies != nil {
+		j, err := p.Cookies.Jar(key)
+		if err != nil {
+			return nil, err
+		}
+		jar = j
+	} else {
+		jar, _ = cookiejar.New(nil)
+	}
 
 	c := &http.Client{
 		Transport:     tr,
@@ -124,10 +204,13 @@ func (p *ProxyTransport) clientForProxy(u *url.URL) (*http.Client, error) {
 	return c, nil
 }
 
+// ProxyError ошибка запроса через прокси; Proxy хранится без логина/пароля
 type ProxyError struct {
 	Proxy string
 	Err   error
 }
 
-func (e ProxyError) Error() string { return "proxy=" + e.Proxy + " err=" + e.Err.Error() }
+func (e ProxyError) Error() string {
+	return "proxy=" + RedactString(e.Proxy) + " err=" + RedactString(e.Err.Error())
+}
 func (e ProxyError) Unwrap() error { return e.Err }
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ProxyCheck результат проверки одного прокси
+type ProxyCheck struct {
+	Proxy   string // адрес прокси, логин/пароль заменены на *** (RedactURL)
+	Status  int
+	Latency time.Duration
+	Blocked string // причина, если вместо страницы пришла капча/антибот
+	Err     error
+}
+
+func (c ProxyCheck) OK() bool {
+	return c.Err == nil && c.Blocked == "" && c.Status > 0 && c.Status < 400
+}
+
+// CheckProxy открывает target через прокси так же, как это делает ProxyTransport (свой клиент, cookie jar и
+// профиль заголовков); profiles может быть nil
+func CheckProxy(ctx context.Context, base *http.Client, proxy, target string, profiles *HeaderProfileSet) ProxyCheck {
+	pt, err := NewProxyTransportWithList(base, []string{proxy})
+	if err != nil {
+		return ProxyCheck{Proxy: redactRaw(proxy), Err: err}
+	}
+	u := pt.rotator.Next()
+	res := ProxyCheck{Proxy: RedactURL(u)}
+	if profiles != nil {
+		pt.Use(ProxyHeaderProfiles(profiles))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+
+	t, err := pt.transportForProxy(u)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+
+	started := time.Now()
+	resp, err := t.Do(req)
+	res.Latency = time.Since(started)
+	if err != nil {
+		res.Err = ProxyError{Proxy: res.Proxy, Err: err}
+		return res
+	}
+	defer resp.Body.Close()
+
+	res.Status = resp.StatusCode
+	if reason, blocked := DetectBlocked(resp); blocked {
+		res.Blocked = reason
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 2<<20))
+	return res
+}
@@ -0,0 +1,226 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// RateLimitConfig параметры token bucket
+type RateLimitConfig struct {
+	RPS   float64 // запросов в секунду, 0 — без ограничения
+	Burst int     // размер корзины (сколько запросов можно отправить пачкой)
+
+	MinRPS         float64 // нижняя граница при снижении скорости
+	DecreaseFactor float64 // во сколько раз умножается скорость на 429 (0..1)
+	RecoverStep    float64 // на сколько rps поднимается скорость после успешного ответа
+}
+
+func (c RateLimitConfig) Enabled() bool { return c.RPS > 0 }
+
+// RateLimiter адаптивный token bucket: снижает скорость на 429/Retry-After и медленно восстанавливает её
+type RateLimiter struct {
+	mu sync.Mutex
+
+	rate    float64
+	maxRate float64
+	minRate float64
+	burst   float64
+
+	decrease    float64
+	recoverStep float64
+
+	tokens       float64
+	last         time.Time
+	blockedUntil time.Time
+}
+
+func NewRateLimiter(cfg RateLimitConfig) *RateLimiter {
+	burst := float64(cfg.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	minRate := cfg.MinRPS
+	if minRate <= 0 || minRate > cfg.RPS {
+		minRate = cfg.RPS / 10
+	}
+	decrease := cfg.DecreaseFactor
+	if decrease <= 0 || decrease >= 1 {
+		decrease = 0.5
+	}
+	recoverStep := cfg.RecoverStep
+	if recoverStep <= 0 {
+		recoverStep = cfg.RPS / 50
+	}
+
+	return &RateLimiter{
+		rate:        cfg.RPS,
+		maxRate:     cfg.RPS,
+		minRate:     minRate,
+		burst:       burst,
+		decrease:    decrease,
+		recoverStep: recoverStep,
+		tokens:      burst,
+		last:        time.Now(),
+	}
+}
+
+// Wait блокируется до появления свободного токена или отмены контекста
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.refill(now)
+
+		var wait time.Duration
+		switch {
+		case now.Before(l.blockedUntil):
+			wait = l.blockedUntil.Sub(now)
+		case l.tokens >= 1:
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		default:
+			wait = time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		}
+		l.mu.Unlock()
+
+		if err := sleepCtx(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// Throttle снижает скорость после 429; retryAfter > 0 дополнительно блокирует корзину на это время
+func (l *RateLimiter) Throttle(retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.rate *= l.decrease
+	if l.rate < l.minRate {
+		l.rate = l.minRate
+	}
+	l.tokens = 0
+	if retryAfter > 0 {
+		if until := time.Now().Add(retryAfter); until.After(l.blockedUntil) {
+			l.blockedUntil = until
+		}
+	}
+}
+
+// Success постепенно возвращает скорость к исходной
+func (l *RateLimiter) Success() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.rate += l.recoverStep
+	if l.rate > l.maxRate {
+		l.rate = l.maxRate
+	}
+}
+
+// Rate текущая скорость (rps)
+func (l *RateLimiter) Rate() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+func (l *RateLimiter) refill(now time.Time) {
+	elapsed := now.Sub(l.last).Seconds()
+	l.last = now
+	if elapsed <= 0 {
+		return
+	}
+	l.tokens += elapsed * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+}
+
+// observe подстраивает скорость под ответ сервера
+func (l *RateLimiter) observe(resp *http.Response, err error) {
+	if err != nil || resp == nil {
+		return
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		l.Throttle(retryAfterDelay(resp))
+		return
+	}
+	if resp.StatusCode < 500 {
+		l.Success()
+	}
+}
+
+// RateLimitTransport ограничивает частоту запросов отдельно на каждый хост
+type RateLimitTransport struct {
+	Base Transport
+
+	cfg      RateLimitConfig
+	mu       sync.Mutex
+	limiters map[string]*RateLimiter
+}
+
+func NewRateLimitTransport(base Transport, cfg RateLimitConfig) *RateLimitTransport {
+	return &RateLimitTransport{
+		Base:     base,
+		cfg:      cfg,
+		limiters: make(map[string]*RateLimiter),
+	}
+}
+
+func (t *RateLimitTransport) Do(req *http.Request) (*http.Response, error) {
+	l := t.limiterFor(req.URL.Host)
+	if err := l.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.Base.Do(req)
+	l.observe(resp, err)
+	return resp, err
+}
+
+func (t *RateLimitTransport) limiterFor(host string) *RateLimiter {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	l, ok := t.limiters[host]
+	if !ok {
+		l = NewRateLimiter(t.cfg)
+		t.limiters[host] = l
+	}
+	return l
+}
+
+// ProxyRateLimit собственный token bucket на каждый прокси
+func ProxyRateLimit(cfg RateLimitConfig) ProxyLayer {
+	return func(_ *url.URL, base Transport) Transport {
+		l := NewRateLimiter(cfg)
+		return TransportFunc(func(req *http.Request) (*http.Response, error) {
+			if err := l.Wait(req.Context()); err != nil {
+				return nil, err
+			}
+			resp, err := base.Do(req)
+			l.observe(resp, err)
+			return resp, err
+		})
+	}
+}
+
+// sleepCtx спит d, прерываясь при отмене контекста
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
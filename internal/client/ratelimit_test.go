@@ -0,0 +1,161 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func approx(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestRateLimiterThrottleAndRecover(t *testing.T) {
+	l := NewRateLimiter(RateLimitConfig{RPS: 10, Burst: 5, MinRPS: 2, RecoverStep: 1})
+
+	// каждый 429 снижает скорость вдвое, но не ниже min_rps
+	for _, want := range []float64{5, 2.5, 2, 2} {
+		l.Throttle(0)
+		if got := l.Rate(); !approx(got, want) {
+			t.Fatalf("rate после 429 = %v, ожидалось %v", got, want)
+		}
+	}
+	// успешные ответы поднимают скорость на recover_step, не выше исходной
+	for _, want := range []float64{3, 4, 5, 6, 7, 8, 9, 10, 10} {
+		l.Success()
+		if got := l.Rate(); !approx(got, want) {
+			t.Fatalf("rate после успеха = %v, ожидалось %v", got, want)
+		}
+	}
+}
+
+func TestRateLimiterDefaults(t *testing.T) {
+	// min_rps по умолчанию — десятая часть rps, recover_step — пятидесятая
+	l := NewRateLimiter(RateLimitConfig{RPS: 10})
+	for range 10 {
+		l.Throttle(0)
+	}
+	if got := l.Rate(); !approx(got, 1) {
+		t.Errorf("нижняя граница = %v, ожидалось 1", got)
+	}
+	l.Success()
+	if got := l.Rate(); !approx(got, 1.2) {
+		t.Errorf("после успеха = %v, ожидалось 1.2", got)
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	l := NewRateLimiter(RateLimitConfig{RPS: 1000, Burst: 2})
+	ctx := context.Background()
+
+	// пачка в пределах burst — без ожидания
+	start := time.Now()
+	for range 2 {
+		if err := l.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d > 50*time.Millisecond {
+		t.Errorf("burst ждал %v", d)
+	}
+
+	// Retry-After блокирует корзину независимо от скорости
+	l.Throttle(100 * time.Millisecond)
+	start = time.Now()
+	if err := l.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 90*time.Millisecond {
+		t.Errorf("после Retry-After 100ms ждали только %v", d)
+	}
+
+	// ожидание прерывается отменой
+	l.Throttle(time.Minute)
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait = %v, ожидалась отмена", err)
+	}
+}
+
+// statusTransport отвечает status с Retry-After для запросов к хосту throttled, остальным — 200
+func statusTransport(throttled string, status int) TransportFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		if req.URL.Host == throttled {
+			return newResponse(req, status, http.Header{"Retry-After": {"1"}}, ""), nil
+		}
+		return newResponse(req, http.StatusOK, nil, ""), nil
+	}
+}
+
+func TestRateLimitTransportPerHost(t *testing.T) {
+	rt := NewRateLimitTransport(statusTransport("kuper.ru", http.StatusTooManyRequests),
+		RateLimitConfig{RPS: 10, Burst: 10, MinRPS: 1, RecoverStep: 1})
+
+	for _, u := range []string{"https://kuper.ru/api/stores/960", "https://cdn.kuper.ru/a.png"} {
+		resp, err := rt.Do(httptest.NewRequest(http.MethodGet, u, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if got := rt.limiterFor("kuper.ru").Rate(); !approx(got, 5) {
+		t.Errorf("kuper.ru после 429: rate = %v, ожидалось 5", got)
+	}
+	if got := rt.limiterFor("cdn.kuper.ru").Rate(); !approx(got, 10) {
+		t.Errorf("cdn.kuper.ru: rate = %v, ожидалось 10 — лимит другого хоста не должен меняться", got)
+	}
+
+	// 5xx не ускоряет и не замедляет
+	rt = NewRateLimitTransport(statusTransport("kuper.ru", http.StatusBadGateway), RateLimitConfig{RPS: 10, Burst: 10})
+	resp, err := rt.Do(httptest.NewRequest(http.MethodGet, "https://kuper.ru/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := rt.limiterFor("kuper.ru").Rate(); !approx(got, 10) {
+		t.Errorf("после 502: rate = %v, ожидалось 10", got)
+	}
+}
+
+func TestProxyRateLimitPerProxy(t *testing.T) {
+	layer := ProxyRateLimit(RateLimitConfig{RPS: 100, Burst: 5})
+	a, _ := url.Parse("http://10.0.0.1:8080")
+	b, _ := url.Parse("http://10.0.0.2:8080")
+
+	// оба прокси ходят на один хост, но 429 получает только первый
+	tooMany := TransportFunc(func(req *http.Request) (*http.Response, error) {
+		return newResponse(req, http.StatusTooManyRequests, http.Header{"Retry-After": {"1"}}, ""), nil
+	})
+	ok := TransportFunc(func(req *http.Request) (*http.Response, error) {
+		return newResponse(req, http.StatusOK, nil, ""), nil
+	})
+	ta, tb := layer(a, tooMany), layer(b, ok)
+
+	do := func(tr Transport) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		req := httptest.NewRequest(http.MethodGet, "https://kuper.ru/", nil).WithContext(ctx)
+		resp, err := tr.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	if err := do(ta); err != nil {
+		t.Fatal(err)
+	}
+	// первый прокси заблокирован Retry-After на секунду, второй — нет
+	if err := do(ta); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("прокси после 429: %v, ожидалось ожидание Retry-After", err)
+	}
+	for range 3 {
+		if err := do(tb); err != nil {
+			t.Errorf("другой прокси: %v", err)
+		}
+	}
+}
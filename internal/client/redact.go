@@ -0,0 +1,37 @@
+package client
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+)
+
+// RedactURL адрес без логина/пароля; пригоден для логов, ошибок, меток метрик и отчётов
+func RedactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	if u.User == nil {
+		return u.String()
+	}
+	// url.User экранирует "*", поэтому маскируем уже собранную строку
+	return RedactString(u.String())
+}
+
+// userinfo до последней @ перед путём: в неэкранированном пароле тоже бывает @;
+// в кавычках (url.Parse цитирует исходный адрес) — и с пробелами
+var (
+	userinfoRe       = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9+.\-]*://)[^/?#\s"']*@`)
+	quotedUserinfoRe = regexp.MustCompile(`("[a-zA-Z][a-zA-Z0-9+.\-]*://)[^"/?#]*@`)
+)
+
+// RedactString вырезает userinfo из всех URL в произвольной строке (например, в тексте ошибки)
+func RedactString(s string) string {
+	s = quotedUserinfoRe.ReplaceAllString(s, "${1}***@")
+	return userinfoRe.ReplaceAllString(s, "${1}***@")
+}
+
+// redactRaw адрес, который не удалось разобрать, в кавычках и без userinfo (в нём бывают и пробелы)
+func redactRaw(s string) string {
+	return RedactString(strconv.Quote(s))
+}
@@ -1,137 +1,309 @@
 package client
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"math/rand"
 	"net"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	"kuperparser/internal/logging"
 )
 
-type RetryTransport struct {
-	Base       Transport
+// ErrorClass класс транспортной ошибки для правил ретрая
+type ErrorClass string
+
+const (
+	ErrorNetwork ErrorClass = "network" // обрывы соединения, DNS и прочие net.Error
+	ErrorTimeout ErrorClass = "timeout" // таймауты
+	ErrorProxy   ErrorClass = "proxy"   // ошибки при работе через прокси
+)
+
+// RetryPolicy правила повторов запроса
+type RetryPolicy struct {
 	MaxRetries int
 
-	BaseDelay time.Duration
-	MaxDelay  time.Duration
+	Statuses []int        // коды ответа, при которых повторяем
+	Errors   []ErrorClass // классы ошибок, при которых повторяем
+
+	BaseDelay     time.Duration
+	MaxDelay      time.Duration
+	Jitter        float64       // разброс задержки: 0.5 => от 0.5x до 1.5x, 0 — без разброса
+	MaxElapsed    time.Duration // общий лимит времени на запрос вместе с повторами, 0 — без лимита
+	MaxRetryAfter time.Duration // верхняя граница ожидания по Retry-After
+
+	// BudgetRatio ограничивает долю ретраев от всех запросов транспорта (0 — без ограничения),
+	// чтобы при массовых отказах ретраи не умножали нагрузку
+	BudgetRatio float64
 }
 
-func (r *RetryTransport) Do(req *http.Request) (*http.Response, error) {
-	var lastErr error
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if len(p.Statuses) == 0 {
+		p.Statuses = []int{http.StatusTooManyRequests, 500, 502, 503, 504}
+	}
+	if len(p.Errors) == 0 {
+		p.Errors = []ErrorClass{ErrorNetwork, ErrorTimeout, ErrorProxy}
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = 300 * time.Millisecond
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = 8 * time.Second
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		p.Jitter = 0.5
+	}
+	if p.MaxRetryAfter <= 0 {
+		p.MaxRetryAfter = 60 * time.Second
+	}
+	return p
+}
 
-	for attempt := 0; attempt <= r.MaxRetries; attempt++ {
-		if err := req.Context().Err(); err != nil {
-			return nil, err
+func (p RetryPolicy) retryStatus(code int) bool {
+	for _, s := range p.Statuses {
+		if s == code {
+			return true
 		}
+	}
+	return false
+}
 
-		curReq := req.Clone(req.Context())
+func (p RetryPolicy) retryError(err error) bool {
+	class, ok := classifyError(err)
+	if !ok {
+		return false
+	}
+	for _, c := range p.Errors {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
 
-		resp, err := r.Base.Do(curReq)
-		if err == nil && resp != nil {
-			if !shouldRetryStatus(resp.StatusCode) {
-				return resp, nil
-			}
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay << attempt
+	if d > p.MaxDelay || d <= 0 {
+		d = p.MaxDelay
+	}
+	j := 1 - p.Jitter + 2*p.Jitter*rand.Float64()
+	return time.Duration(float64(d) * j)
+}
 
-			io.Copy(io.Discard, io.LimitReader(resp.Body, 32*1024))
-			resp.Body.Close()
+// RetryBudget общий на транспорт лимит ретраев: не больше ratio от числа запросов (плюс небольшой запас на старте)
+type RetryBudget struct {
+	ratio   float64
+	reserve int
+
+	mu       sync.Mutex
+	requests int
+	retries  int
+}
+
+func NewRetryBudget(ratio float64) *RetryBudget {
+	return &RetryBudget{ratio: ratio, reserve: 10}
+}
+
+func (b *RetryBudget) request() {
+	b.mu.Lock()
+	b.requests++
+	b.mu.Unlock()
+}
+
+func (b *RetryBudget) take() bool {
+	if b.ratio <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if float64(b.retries) >= float64(b.reserve)+b.ratio*float64(b.requests) {
+		return false
+	}
+	b.retries++
+	return true
+}
+
+type RetryTransport struct {
+	Base    Transport
+	Policy  RetryPolicy
+	Metrics *Metrics     // может быть nil
+	Logger  *slog.Logger // может быть nil
 
-			lastErr = fmt.Errorf("retryable status=%d", resp.StatusCode)
+	once   sync.Once
+	budget *RetryBudget
+}
+
+func NewRetryTransport(base Transport, policy RetryPolicy) *RetryTransport {
+	return &RetryTransport{Base: base, Policy: policy}
+}
+
+func (r *RetryTransport) Do(req *http.Request) (*http.Response, error) {
+	r.once.Do(func() {
+		r.Policy = r.Policy.withDefaults()
+		r.budget = NewRetryBudget(r.Policy.BudgetRatio)
+	})
+	policy := r.Policy
+	ctx := req.Context()
+	logger := logging.FromContext(ctx, r.Logger).With("url", RedactURL(req.URL))
+
+	start := time.Now()
+	r.budget.request()
+
+	for attempt := 0; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 
-			log.Printf("[RETRY] attempt=%d/%d status=%d url=%s",
-				attempt+1, r.MaxRetries+1, resp.StatusCode, req.URL.String(),
-			)
+		var (
+			retryAfter time.Duration
+			reason     string
+		)
 
-			// 429: можем подождать Retry-After
-			if resp.StatusCode == http.StatusTooManyRequests {
-				if d := retryAfterDelay(resp); d > 0 && attempt < r.MaxRetries {
-					log.Printf("[RETRY] 429 retry-after=%s", d)
-					time.Sleep(d)
-					continue
-				}
+		resp, err := r.Base.Do(req.Clone(ctx))
+		if err == nil {
+			if !policy.retryStatus(resp.StatusCode) {
+				return resp, nil
 			}
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), policy.MaxRetryAfter)
+			reason = strconv.Itoa(resp.StatusCode)
+
+			logger.Warn("повторяемый статус ответа",
+				"attempt", attempt+1, "max_attempts", policy.MaxRetries+1, "status", resp.StatusCode)
 		} else {
-			// err != nil
-			if err != nil && !shouldRetryError(err) {
+			// отменён сам запрос (ctx вызывающего) — повторять нечего; таймаут http.Client сюда не попадает
+			if ctx.Err() != nil || !policy.retryError(err) {
 				return nil, err
 			}
-			lastErr = err
+			class, _ := classifyError(err)
+			reason = string(class)
 
 			if pe := (ProxyError{}); errors.As(err, &pe) {
-				log.Printf("[RETRY] attempt=%d/%d proxy=%s err=%v url=%s",
-					attempt+1, r.MaxRetries+1, pe.Proxy, pe.Err, req.URL.String(),
-				)
+				logger.Warn("повторяемая ошибка запроса",
+					"attempt", attempt+1, "max_attempts", policy.MaxRetries+1,
+					"proxy", RedactString(pe.Proxy), "err", RedactString(pe.Err.Error()))
 			} else {
-				log.Printf("[RETRY] attempt=%d/%d err=%v url=%s",
-					attempt+1, r.MaxRetries+1, err, req.URL.String(),
-				)
+				logger.Warn("повторяемая ошибка запроса",
+					"attempt", attempt+1, "max_attempts", policy.MaxRetries+1, "err", RedactString(err.Error()))
 			}
 		}
 
-		if attempt == r.MaxRetries {
-			break
+		d, ok := r.nextDelay(logger, attempt, start, retryAfter)
+		if !ok {
+			// повторов больше не будет: последний ответ отдаётся как есть,
+			// чтобы вызывающий разобрал статус (kuper.APIError, ErrRateLimited)
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 32*1024))
+			resp.Body.Close()
 		}
-		d := r.backoff(attempt)
-		log.Printf("[RETRY] sleeping=%s before next attempt", d)
-		time.Sleep(d)
-	}
 
-	return nil, lastErr
+		r.Metrics.retry(req, reason)
+		logger.Debug("пауза перед повтором", "attempt", attempt+1, "delay", d)
+		if err := sleepCtx(ctx, d); err != nil {
+			return nil, err
+		}
+	}
 }
 
-func shouldRetryStatus(code int) bool {
-	if code == http.StatusTooManyRequests {
-		return true
+// nextDelay задержка перед следующей попыткой; false — попытки, время или бюджет ретраев исчерпаны
+func (r *RetryTransport) nextDelay(logger *slog.Logger, attempt int, start time.Time, retryAfter time.Duration) (time.Duration, bool) {
+	policy := r.Policy
+	if attempt >= policy.MaxRetries {
+		return 0, false
 	}
-	return code >= 500 && code <= 599
+
+	// Retry-After (429/503) важнее собственного backoff
+	d := retryAfter
+	if d <= 0 {
+		d = policy.backoff(attempt)
+	}
+
+	if policy.MaxElapsed > 0 && time.Since(start)+d > policy.MaxElapsed {
+		logger.Warn("повторы прекращены: превышено общее время на запрос (http.retry.max_elapsed_seconds)", "max_elapsed", policy.MaxElapsed)
+		return 0, false
+	}
+	if !r.budget.take() {
+		logger.Warn("повторы прекращены: исчерпан бюджет ретраев (http.retry.budget_ratio)")
+		return 0, false
+	}
+	return d, true
 }
 
-func shouldRetryError(err error) bool {
+// classifyError определяет класс ошибки; false — ошибка не подлежит ретраю в принципе.
+// Отмену контекста запроса проверяет вызывающий: таймаут http.Client тоже удовлетворяет
+// errors.Is(err, context.DeadlineExceeded), но это net.Error с Timeout() и класс timeout
+func classifyError(err error) (ErrorClass, bool) {
 	if err == nil {
-		return false
+		return "", false
 	}
 
-	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-		return false
+	// разомкнутый breaker — ретраи только добавят нагрузку; блокировку антиботом ретраи не снимут,
+	// ротацию прокси при ней решает antibot.policy, а не ретраи транспорта
+	if errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrBlocked) {
+		return "", false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorTimeout, true
 	}
 
 	var pe ProxyError
 	if errors.As(err, &pe) {
-		return true
+		return ErrorProxy, true
 	}
 
-	var netErr net.Error
-	if errors.As(err, &netErr) {
-		return true
+	if netErr != nil {
+		return ErrorNetwork, true
 	}
 
-	return false
+	return "", false
 }
 
-func (r *RetryTransport) backoff(attempt int) time.Duration {
-	d := r.BaseDelay << attempt
-	if d > r.MaxDelay {
-		d = r.MaxDelay
+// ParseErrorClasses разбирает имена классов ошибок из конфига
+func ParseErrorClasses(names []string) ([]ErrorClass, error) {
+	out := make([]ErrorClass, 0, len(names))
+	for _, n := range names {
+		switch c := ErrorClass(strings.ToLower(strings.TrimSpace(n))); c {
+		case ErrorNetwork, ErrorTimeout, ErrorProxy:
+			out = append(out, c)
+		default:
+			return nil, fmt.Errorf("неизвестный класс ошибки %q (ожидается network|timeout|proxy)", n)
+		}
 	}
-	j := 0.5 + rand.Float64()
-	return time.Duration(float64(d) * j)
+	return out, nil
 }
 
 func retryAfterDelay(resp *http.Response) time.Duration {
-	ra := resp.Header.Get("Retry-After")
+	return parseRetryAfter(resp.Header.Get("Retry-After"), 60*time.Second)
+}
+
+// parseRetryAfter понимает оба формата Retry-After: число секунд и HTTP-date
+func parseRetryAfter(ra string, maxDelay time.Duration) time.Duration {
+	ra = strings.TrimSpace(ra)
 	if ra == "" {
 		return 0
 	}
-	sec, err := strconv.Atoi(ra)
-	if err != nil || sec <= 0 {
+
+	var d time.Duration
+	if sec, err := strconv.Atoi(ra); err == nil {
+		d = time.Duration(sec) * time.Second
+	} else if t, err := http.ParseTime(ra); err == nil {
+		d = time.Until(t)
+	}
+
+	if d <= 0 {
 		return 0
 	}
-	if sec > 60 {
-		sec = 60
+	if d > maxDelay {
+		d = maxDelay
 	}
-	return time.Duration(sec) * time.Second
+	return d
 }
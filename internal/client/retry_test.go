@@ -0,0 +1,174 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	max := time.Minute
+	tests := []struct {
+		name string
+		in   string
+		want time.Duration
+	}{
+		{"пусто", "", 0},
+		{"секунды", "3", 3 * time.Second},
+		{"пробелы", " 2 ", 2 * time.Second},
+		{"ноль", "0", 0},
+		{"отрицательное", "-5", 0},
+		{"не больше max", "3600", max},
+		{"дата в прошлом", "Wed, 21 Oct 2015 07:28:00 GMT", 0},
+		{"мусор", "скоро", 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.in, max); got != tt.want {
+			t.Errorf("%s: parseRetryAfter(%q) = %v, ожидалось %v", tt.name, tt.in, got, tt.want)
+		}
+	}
+
+	// HTTP-date: до неё примерно 30 секунд с точностью до секунды формата
+	at := time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(at, max); got < 28*time.Second || got > 30*time.Second {
+		t.Errorf("parseRetryAfter(%q) = %v, ожидалось около 30s", at, got)
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		class ErrorClass
+		ok    bool
+	}{
+		{"nil", nil, "", false},
+		{"обрыв соединения", &net.OpError{Op: "read", Net: "tcp", Err: ErrChaos}, ErrorNetwork, true},
+		{"прокси", ProxyError{Proxy: "http://10.0.0.1:8080", Err: errors.New("connection refused")}, ErrorProxy, true},
+		{"breaker", &CircuitOpenError{Key: "host=kuper.ru"}, "", false},
+		{"антибот", &BlockedError{URL: "https://kuper.ru/", Status: 403, Reason: "captcha"}, "", false},
+		{"прочее", errors.New("что-то другое"), "", false},
+	}
+	for _, tt := range tests {
+		class, ok := classifyError(tt.err)
+		if class != tt.class || ok != tt.ok {
+			t.Errorf("%s: classifyError = %q, %v, ожидалось %q, %v", tt.name, class, ok, tt.class, tt.ok)
+		}
+	}
+}
+
+// таймаут http.Client тоже удовлетворяет errors.Is(err, context.DeadlineExceeded), но повторяется как timeout
+func TestClassifyClientTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	c := &http.Client{Timeout: 20 * time.Millisecond}
+	_, err := c.Get(srv.URL)
+	if err == nil {
+		t.Fatal("ожидался таймаут")
+	}
+	if class, ok := classifyError(err); class != ErrorTimeout || !ok {
+		t.Errorf("classifyError(%v) = %q, %v, ожидалось timeout", err, class, ok)
+	}
+}
+
+// fakeTransport отдаёт ответы по очереди и считает запросы
+type fakeTransport struct {
+	calls int
+	do    func(req *http.Request, call int) (*http.Response, error)
+}
+
+func (f *fakeTransport) Do(req *http.Request) (*http.Response, error) {
+	f.calls++
+	return f.do(req, f.calls)
+}
+
+func fastPolicy(retries int) RetryPolicy {
+	return RetryPolicy{MaxRetries: retries, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, MaxRetryAfter: time.Millisecond}
+}
+
+func TestRetryTransportRecovers(t *testing.T) {
+	base := &fakeTransport{do: func(req *http.Request, call int) (*http.Response, error) {
+		if call < 3 {
+			return newResponse(req, http.StatusBadGateway, nil, "bad gateway"), nil
+		}
+		return newResponse(req, http.StatusOK, nil, `{"ok":true}`), nil
+	}}
+	rt := NewRetryTransport(base, fastPolicy(3))
+
+	req := httptest.NewRequest(http.MethodGet, "https://kuper.ru/api/stores/960", nil)
+	resp, err := rt.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || base.calls != 3 {
+		t.Errorf("статус %d после %d запросов, ожидалось 200 после 3", resp.StatusCode, base.calls)
+	}
+}
+
+// после исчерпания попыток вызывающий получает последний ответ, а не ошибку ретраев
+func TestRetryTransportReturnsLastResponse(t *testing.T) {
+	base := &fakeTransport{do: func(req *http.Request, call int) (*http.Response, error) {
+		h := http.Header{"Retry-After": {"1"}}
+		return newResponse(req, http.StatusTooManyRequests, h, `{"code":"too_many_requests"}`), nil
+	}}
+	rt := NewRetryTransport(base, fastPolicy(2))
+
+	req := httptest.NewRequest(http.MethodGet, "https://kuper.ru/api/stores/960", nil)
+	resp, err := rt.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || base.calls != 3 {
+		t.Errorf("статус %d после %d запросов, ожидалось 429 после 3", resp.StatusCode, base.calls)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != `{"code":"too_many_requests"}` {
+		t.Errorf("тело последнего ответа %q", body)
+	}
+}
+
+func TestRetryTransportNotRetried(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"антибот", &BlockedError{URL: "https://kuper.ru/", Status: 403, Reason: "captcha"}},
+		{"breaker", &CircuitOpenError{Key: "host=kuper.ru"}},
+	}
+	for _, tt := range tests {
+		base := &fakeTransport{do: func(*http.Request, int) (*http.Response, error) { return nil, tt.err }}
+		rt := NewRetryTransport(base, fastPolicy(3))
+
+		_, err := rt.Do(httptest.NewRequest(http.MethodGet, "https://kuper.ru/", nil))
+		if !errors.Is(err, tt.err) || base.calls != 1 {
+			t.Errorf("%s: ошибка %v после %d запросов, ожидался 1 запрос без повторов", tt.name, err, base.calls)
+		}
+	}
+}
+
+func TestRetryTransportCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	base := &fakeTransport{do: func(*http.Request, int) (*http.Response, error) {
+		cancel()
+		return nil, &net.OpError{Op: "read", Net: "tcp", Err: context.Canceled}
+	}}
+	rt := NewRetryTransport(base, fastPolicy(3))
+
+	req := httptest.NewRequest(http.MethodGet, "https://kuper.ru/", nil).WithContext(ctx)
+	if _, err := rt.Do(req); err == nil || base.calls != 1 {
+		t.Errorf("ошибка %v после %d запросов, ожидался 1 запрос без повторов", err, base.calls)
+	}
+}
@@ -2,7 +2,10 @@ package client
 
 import (
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -10,6 +13,11 @@ type Transport interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
+// TransportFunc адаптер обычной функции к интерфейсу Transport
+type TransportFunc func(req *http.Request) (*http.Response, error)
+
+func (f TransportFunc) Do(req *http.Request) (*http.Response, error) { return f(req) }
+
 type ProxyMode string
 
 const (
@@ -20,14 +28,55 @@ const (
 
 type TransportConfig struct {
 	Timeout     time.Duration
-	Retries     int
+	Retry       RetryPolicy
 	Workers     int
 	ProxyMode   ProxyMode
 	ProxyList   []string
 	RotationURL string
+
+	RateLimit      RateLimitConfig // общий лимит на каждый хост
+	ProxyRateLimit RateLimitConfig // отдельный лимит на каждый прокси
+
+	Breaker      BreakerConfig // breaker на каждый хост
+	ProxyBreaker BreakerConfig // breaker на каждый прокси
+
+	Cassette CassetteConfig // запись/воспроизведение ответов
+	Cache    CacheConfig    // дисковый кэш ответов
+
+	HeaderProfiles *HeaderProfileSet // nil — заголовки только из kuper.applyDefaultHeaders
+	Block          BlockConfig       // реакция на страницы антибота
+
+	Cookies *CookieStore // nil — cookie только в памяти
+	Warmup  WarmupConfig // прогрев каждой сессии перед запросами к API
+
+	Chaos ChaosConfig // внесение сбоев для проверки устойчивости, по умолчанию выключено
+
+	Metrics *Metrics     // nil — метрики не собираются
+	Logger  *slog.Logger // nil — slog.Default()
+
+	// Order порядок слоёв над базовым транспортом (снаружи внутрь), пусто — DefaultOrder.
+	// Базовый транспорт (HTTP/прокси, кассеты, антибот, прогрев, профили, метрики попыток) не переставляется
+	Order []string
+	// Middlewares дополнительные слои по имени; имена должны быть указаны в Order
+	Middlewares map[string]Middleware
 }
 
 func Build(baseHTTP *http.Client, cfg TransportConfig) (Transport, error) {
+	if err := cfg.Chaos.Validate(); err != nil {
+		return nil, fmt.Errorf("chaos: %w", err)
+	}
+
+	if cfg.Cookies != nil {
+		jar, err := cfg.Cookies.Jar("direct")
+		if err != nil {
+			return nil, err
+		}
+		// клиент вызывающего не меняем
+		c := *baseHTTP
+		c.Jar = jar
+		baseHTTP = &c
+	}
+
 	var t Transport = &HTTPTransport{Client: baseHTTP}
 
 	switch cfg.ProxyMode {
@@ -37,34 +86,124 @@ func Build(baseHTTP *http.Client, cfg TransportConfig) (Transport, error) {
 		if err != nil {
 			return nil, err
 		}
+		useProxyLayers(pt, cfg)
 		t = pt
 	case ProxyList:
 		pt, err := NewProxyTransportWithList(baseHTTP, cfg.ProxyList)
 		if err != nil {
 			return nil, err
 		}
+		useProxyLayers(pt, cfg)
 		t = pt
 	default:
-		return nil, fmt.Errorf("Не удалось получить режим прокси: %s", string(cfg.ProxyMode))
+		return nil, fmt.Errorf("неизвестный режим прокси: %s", string(cfg.ProxyMode))
 	}
 
-	// retry layer (around proxy)
-	if cfg.Retries > 0 {
-		t = &RetryTransport{
-			Base:       t,
-			MaxRetries: cfg.Retries,
-			BaseDelay:  300 * time.Millisecond,
-			MaxDelay:   8 * time.Second,
+	// record/replay: в режиме replay сеть и прокси не используются
+	switch cfg.Cassette.Mode {
+	case CassetteOff, "":
+	case CassetteRecord, CassetteReplay:
+		cas, err := LoadCassette(cfg.Cassette.Path)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Cassette.Mode == CassetteReplay {
+			t = &ReplayTransport{Cassette: cas}
+		} else {
+			t = &RecordTransport{Base: t, Cassette: cas}
 		}
+	default:
+		return nil, fmt.Errorf("неизвестный режим кассеты: %s", string(cfg.Cassette.Mode))
+	}
+
+	// сбои вносятся под всеми слоями; в режиме прокси — на каждом прокси (см. useProxyLayers)
+	if cfg.Chaos.Enabled() && !proxyChaos(cfg) {
+		t = NewChaosTransport(t, cfg.Chaos)
 	}
 
-	// limiter layer (outermost)
-	if cfg.Workers > 0 {
-		t = &LimitedTransport{
-			Base:    t,
-			Limiter: NewLimiter(cfg.Workers),
+	// обнаружение антибота для прямых запросов и кассет; в режиме прокси — на каждом прокси
+	if cfg.Cassette.Mode == CassetteReplay || cfg.ProxyMode == ProxyDisabled || cfg.ProxyMode == "" {
+		t = &BlockDetectTransport{Base: t, Cfg: cfg.Block}
+	}
+
+	direct := cfg.ProxyMode == ProxyDisabled || cfg.ProxyMode == ""
+
+	// прогрев сессии для прямых запросов; в режиме прокси — каждой прокси-сессии
+	if cfg.Warmup.Enabled() && direct && cfg.Cassette.Mode != CassetteReplay {
+		t = &WarmupTransport{Base: t, Cfg: cfg.Warmup, Logger: cfg.Logger}
+	}
+
+	// профиль браузера для прямых запросов; в режиме прокси — свой на каждый прокси
+	if cfg.HeaderProfiles != nil && direct {
+		t = &ProfileTransport{Base: t, Profile: cfg.HeaderProfiles.ForSession("direct")}
+	}
+
+	// метрики попыток; в режиме прокси они навешиваются на каждый прокси отдельно
+	if cfg.Metrics != nil {
+		switch {
+		case cfg.Cassette.Mode == CassetteReplay:
+			t = &MetricsTransport{Base: t, Metrics: cfg.Metrics, Proxy: "replay"}
+		case cfg.ProxyMode == ProxyDisabled || cfg.ProxyMode == "":
+			t = &MetricsTransport{Base: t, Metrics: cfg.Metrics, Proxy: "direct"}
 		}
 	}
 
+	// слои над базовым транспортом в порядке cfg.Order
+	mws, err := buildMiddlewares(cfg)
+	if err != nil {
+		return nil, err
+	}
+	t = Chain(t, mws...)
+
 	return t, nil
 }
+
+func useProxyLayers(pt *ProxyTransport, cfg TransportConfig) {
+	if cfg.ProxyBreaker.Enabled() {
+		pt.Use(ProxyCircuitBreaker(cfg.ProxyBreaker))
+	}
+	if cfg.ProxyRateLimit.Enabled() {
+		pt.Use(ProxyRateLimit(cfg.ProxyRateLimit))
+	}
+	pt.RotateOnBlock = cfg.Block.Policy != BlockAbort
+	pt.Use(ProxyBlockDetect(cfg.Block))
+	pt.Cookies = cfg.Cookies
+	if cfg.Warmup.Enabled() {
+		pt.Use(ProxyWarmup(cfg.Warmup, cfg.Logger))
+	}
+	if cfg.HeaderProfiles != nil {
+		pt.Use(ProxyHeaderProfiles(cfg.HeaderProfiles))
+	}
+	if cfg.Metrics != nil {
+		pt.Use(ProxyMetrics(cfg.Metrics))
+	}
+	if proxyChaos(cfg) {
+		pt.Use(ProxyChaos(cfg.Chaos))
+	}
+}
+
+// proxyChaos сбои вносятся на каждом прокси, а не над общим транспортом;
+// при записи кассеты — над ней, чтобы внесённые сбои не попали в запись
+func proxyChaos(cfg TransportConfig) bool {
+	proxied := cfg.ProxyMode == ProxyList || cfg.ProxyMode == ProxyRotation
+	return cfg.Chaos.Enabled() && proxied && (cfg.Cassette.Mode == CassetteOff || cfg.Cassette.Mode == "")
+}
+
+// newResponse собирает синтетический ответ (кэш, кассеты)
+func newResponse(req *http.Request, status int, header http.Header, body string) *http.Response {
+	h := header.Clone()
+	if h == nil {
+		h = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"kuperparser/internal/logging"
+)
+
+type WarmupConfig struct {
+	URLs []string // страницы, которые открываются в начале каждой сессии (главная и страница магазина)
+}
+
+func (c WarmupConfig) Enabled() bool { return len(c.URLs) > 0 }
+
+// maxWarmupAttempts сколько раз сессия пробует прогреться, если прогрев не удался
+const maxWarmupAttempts = 3
+
+// warmupProfileHeaders заголовки профиля браузера, которые прогрев берёт из запроса сессии
+var warmupProfileHeaders = []string{"User-Agent", "Accept-Language", "Sec-Ch-Ua", "Sec-Ch-Ua-Mobile", "Sec-Ch-Ua-Platform"}
+
+// WarmupTransport перед первым запросом сессии открывает страницы сайта как обычный браузер,
+// чтобы сессия получила cookie (в том числе выбранного магазина) до обращения к API.
+// Ошибки прогрева не прерывают основной запрос; неудачный прогрев повторяется перед следующими запросами
+type WarmupTransport struct {
+	Base   Transport
+	Cfg    WarmupConfig
+	Logger *slog.Logger
+	Proxy  string
+
+	mu       sync.Mutex
+	done     bool
+	attempts int
+}
+
+func (t *WarmupTransport) Do(req *http.Request) (*http.Response, error) {
+	// запросы сессии ждут прогрева, как ждали бы его в браузере
+	t.mu.Lock()
+	if !t.done && t.attempts < maxWarmupAttempts {
+		t.attempts++
+		t.done = t.warm(req)
+	}
+	t.mu.Unlock()
+	return t.Base.Do(req)
+}
+
+// warm открывает все страницы прогрева; false — хотя бы одна не открылась или упёрлась в антибот
+func (t *WarmupTransport) warm(orig *http.Request) bool {
+	// прогрев не должен отменяться вместе с первым запросом, но значения контекста (логгер) сохраняем
+	ctx := context.WithoutCancel(orig.Context())
+	logger := logging.FromContext(ctx, t.Logger)
+	if t.Proxy != "" {
+		logger = logger.With("proxy", t.Proxy)
+	}
+
+	ok := true
+	for _, raw := range t.Cfg.URLs {
+		u, err := url.Parse(raw)
+		if err != nil {
+			logger.Warn("прогрев: некорректный адрес", "url", raw, "err", err)
+			continue
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			continue
+		}
+		// тот же отпечаток браузера, что и у запросов к API: профиль заголовков уже применён к orig
+		for _, name := range warmupProfileHeaders {
+			if v := orig.Header.Values(name); len(v) > 0 {
+				req.Header[name] = v
+			}
+		}
+		req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8")
+		req.Header.Set("Upgrade-Insecure-Requests", "1")
+		req.Header.Set("Sec-Fetch-Site", "none")
+		req.Header.Set("Sec-Fetch-Mode", "navigate")
+		req.Header.Set("Sec-Fetch-Dest", "document")
+		req.Header.Set("Sec-Fetch-User", "?1")
+
+		resp, err := t.Base.Do(req)
+		if err != nil {
+			logger.Warn("прогрев: ошибка запроса", "url", RedactURL(u), "attempt", t.attempts, "err", RedactString(err.Error()))
+			ok = false
+			continue
+		}
+		if reason, blocked := DetectBlocked(resp); blocked {
+			logger.Warn("прогрев: страница антибота", "url", RedactURL(u), "attempt", t.attempts, "reason", reason)
+			ok = false
+		}
+		if resp.StatusCode >= http.StatusBadRequest {
+			logger.Warn("прогрев: ошибочный статус ответа", "url", RedactURL(u), "attempt", t.attempts, "status", resp.StatusCode)
+			ok = false
+		}
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 2<<20))
+		resp.Body.Close()
+
+		logger.Debug("прогрев: страница открыта", "url", RedactURL(u), "status", resp.StatusCode)
+	}
+	return ok
+}
+
+// ProxyWarmup прогрев отдельно для каждой прокси-сессии
+func ProxyWarmup(cfg WarmupConfig, logger *slog.Logger) ProxyLayer {
+	return func(proxy *url.URL, base Transport) Transport {
+		return &WarmupTransport{Base: base, Cfg: cfg, Logger: logger, Proxy: proxy.Host}
+	}
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWarmupProfileHeaders(t *testing.T) {
+	var warm []http.Header
+	base := TransportFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get("Sec-Fetch-Mode") == "navigate" {
+			warm = append(warm, req.Header.Clone())
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(""))}, nil
+	})
+
+	profile := HeaderProfile{
+		UserAgent:       "Mozilla/5.0 Chrome/124.0",
+		SecCHUA:         `"Chromium";v="124"`,
+		SecCHUAMobile:   "?0",
+		SecCHUAPlatform: `"Windows"`,
+		AcceptLanguage:  "ru-RU,ru;q=0.9",
+	}
+	wt := &WarmupTransport{Base: base, Cfg: WarmupConfig{URLs: []string{"https://kuper.ru/", "https://kuper.ru/stores/960"}}}
+	ct := &ProfileTransport{Base: wt, Profile: profile}
+
+	resp, err := ct.Do(httptest.NewRequest(http.MethodGet, "https://kuper.ru/api/stores/960", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if len(warm) != 2 {
+		t.Fatalf("открыто %d страниц прогрева, ожидалось 2", len(warm))
+	}
+	want := map[string]string{
+		"User-Agent":         profile.UserAgent,
+		"Accept-Language":    profile.AcceptLanguage,
+		"Sec-Ch-Ua":          profile.SecCHUA,
+		"Sec-Ch-Ua-Mobile":   profile.SecCHUAMobile,
+		"Sec-Ch-Ua-Platform": profile.SecCHUAPlatform,
+	}
+	for name, v := range want {
+		if got := warm[0].Get(name); got != v {
+			t.Errorf("%s прогрева = %q, ожидалось %q", name, got, v)
+		}
+	}
+	if !strings.HasPrefix(warm[0].Get("Accept"), "text/html") {
+		t.Errorf("Accept прогрева = %q, ожидалась навигация", warm[0].Get("Accept"))
+	}
+}
@@ -1,7 +1,14 @@
 package config
 
 import (
+	"bytes"
+	"errors"
+	"io"
 	"os"
+	"regexp"
+	"time"
+
+	"kuperparser/internal/client"
 
 	"gopkg.in/yaml.v3"
 )
@@ -13,7 +20,14 @@ type Config struct {
 	} `yaml:"kuper"`
 
 	Departments struct {
-		Names []string `yaml:"names"`
+		Names       []string `yaml:"names"`        // названия или селекторы slug:/id:/glob:/re:, см. ParseSelector
+		Strict      bool     `yaml:"strict"`       // любой ненайденный отдел — ошибка
+		MaxDistance int      `yaml:"max_distance"` // допустимое число опечаток в названии (не больше четверти его длины), 0 — только точное совпадение
+
+		All         bool     `yaml:"all"`          // все отделы магазина (type=department) вместо names
+		Exclude     []string `yaml:"exclude"`      // отделы, которые не обходим; синтаксис как у names
+		MinProducts int      `yaml:"min_products"` // пропускать отделы, где товаров меньше
+		MaxProducts int      `yaml:"max_products"` // пропускать отделы, где товаров больше, 0 — без ограничения
 	} `yaml:"departments"`
 
 	Pagination struct {
@@ -24,34 +38,175 @@ type Config struct {
 	HTTP struct {
 		TimeoutSeconds int `yaml:"timeout_seconds"`
 		Retries        int `yaml:"retries"`
+
+		Retry struct {
+			Statuses             []int    `yaml:"statuses"`
+			Errors               []string `yaml:"errors"`
+			BaseDelayMS          int      `yaml:"base_delay_ms"`
+			MaxDelayMS           int      `yaml:"max_delay_ms"`
+			Jitter               float64  `yaml:"jitter"`
+			MaxElapsedSeconds    int      `yaml:"max_elapsed_seconds"`
+			MaxRetryAfterSeconds int      `yaml:"max_retry_after_seconds"`
+			BudgetRatio          float64  `yaml:"budget_ratio"`
+		} `yaml:"retry"`
+
+		Cassette struct {
+			Mode string `yaml:"mode"`
+			Path string `yaml:"path"`
+		} `yaml:"cassette"`
+
+		Middleware []string `yaml:"middleware"`
 	} `yaml:"http"`
 
 	Proxy struct {
 		Mode        string   `yaml:"mode"`
 		List        []string `yaml:"list"`
 		RotationURL string   `yaml:"rotation_url"`
+
+		// секреты вне config.yaml
+		ListFile    string `yaml:"list_file"`
+		UsernameEnv string `yaml:"username_env"`
+		PasswordEnv string `yaml:"password_env"`
 	} `yaml:"proxy"`
 
+	Headers struct {
+		ProfilesFile string `yaml:"profiles_file"`
+		Profile      string `yaml:"profile"`
+	} `yaml:"headers"`
+
+	Session struct {
+		CookiesDir string            `yaml:"cookies_dir"`
+		Warmup     bool              `yaml:"warmup"`
+		WarmupURLs []string          `yaml:"warmup_urls"`
+		Cookies    map[string]string `yaml:"cookies"`
+	} `yaml:"session"`
+
+	AntiBot struct {
+		Policy          string `yaml:"policy"`
+		CooldownSeconds int    `yaml:"cooldown_seconds"`
+	} `yaml:"antibot"`
+
+	RateLimit struct {
+		RPS           float64 `yaml:"rps"`
+		Burst         int     `yaml:"burst"`
+		PerProxyRPS   float64 `yaml:"per_proxy_rps"`
+		PerProxyBurst int     `yaml:"per_proxy_burst"`
+		MinRPS        float64 `yaml:"min_rps"`
+		RecoverStep   float64 `yaml:"recover_step"`
+	} `yaml:"rate_limit"`
+
+	CircuitBreaker struct {
+		FailureThreshold         int    `yaml:"failure_threshold"`
+		PerProxyFailureThreshold int    `yaml:"per_proxy_failure_threshold"`
+		OpenSeconds              int    `yaml:"open_seconds"`
+		HalfOpenRequests         int    `yaml:"half_open_requests"`
+		OnOpen                   string `yaml:"on_open"`
+		MaxPauses                int    `yaml:"max_pauses"`
+	} `yaml:"circuit_breaker"`
+
+	Cache struct {
+		Enabled    bool   `yaml:"enabled"`
+		Directory  string `yaml:"directory"`
+		TTLSeconds int    `yaml:"ttl_seconds"`
+		MaxMB      int    `yaml:"max_mb"`
+	} `yaml:"cache"`
+
+	// Chaos внесение сбоев для проверки устойчивости; вероятности от 0 до 1 на запрос
+	Chaos struct {
+		LatencyRate       float64 `yaml:"latency_rate"`
+		LatencyMS         int     `yaml:"latency_ms"`
+		ErrorRate         float64 `yaml:"error_rate"`
+		RateLimitRate     float64 `yaml:"rate_limit_rate"`
+		RetryAfterSeconds int     `yaml:"retry_after_seconds"`
+		ServerErrorRate   float64 `yaml:"server_error_rate"`
+		ChallengeRate     float64 `yaml:"challenge_rate"`
+		TruncateRate      float64 `yaml:"truncate_rate"`
+		Seed              int64   `yaml:"seed"`
+	} `yaml:"chaos"`
+
+	// Failure что делать с ошибкой отдела или страницы во время обхода
+	Failure struct {
+		Policy          string `yaml:"policy"`            // fail-fast | skip-department | skip-page
+		PageRetries     int    `yaml:"page_retries"`      // повторов страницы после ошибки (поверх ретраев транспорта)
+		RetryDelayMS    int    `yaml:"retry_delay_ms"`    // пауза перед повтором страницы, растёт с каждой попыткой
+		MaxSkippedPages int    `yaml:"max_skipped_pages"` // skip-page: после стольких пропущенных страниц подряд отдел бросается, 0 — без ограничения
+		MaxErrors       int    `yaml:"max_errors"`        // после стольких ошибок за запуск обход прерывается, 0 — без ограничения
+	} `yaml:"failure"`
+
+	// Quality проверка извлечённых товаров перед записью
+	Quality struct {
+		Rules           []string `yaml:"rules"`             // required | price | url | duplicate | outlier; пусто — без проверок
+		DropInvalid     bool     `yaml:"drop_invalid"`      // не записывать строки с нарушениями (кроме outlier)
+		MaxInvalidRatio float64  `yaml:"max_invalid_ratio"` // доля строк с нарушениями, выше которой запуск неудачен; 0 — не проверять
+		OutlierRatio    float64  `yaml:"outlier_ratio"`     // цена изменилась с прошлого запуска больше чем в N раз — выброс
+	} `yaml:"quality"`
+
 	Concurrency struct {
 		Workers int `yaml:"workers"`
 	} `yaml:"concurrency"`
 
+	Log struct {
+		Level  string `yaml:"level"`
+		Format string `yaml:"format"`
+	} `yaml:"log"`
+
+	Metrics struct {
+		Listen   string `yaml:"listen"`
+		Textfile string `yaml:"textfile"`
+	} `yaml:"metrics"`
+
 	Output struct {
 		Directory string `yaml:"directory"`
 		Format    string `yaml:"format"`
+		Decimal   string `yaml:"decimal"` // десятичный разделитель цен в CSV: "," или "."
 	} `yaml:"output"`
+
+	decodeErrors []string // неизвестные ключи и ошибки типов из YAML
 }
 
+var unknownFieldRe = regexp.MustCompile(`field (\S+) not found in type .*`)
+
+// Load читает конфиг: значения по умолчанию (Default), затем YAML, затем переменные окружения KUPER_*.
+// Неизвестные ключи не прерывают чтение, а возвращаются из Validate вместе с остальными ошибками
 func Load(path string) (*Config, error) {
 	b, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
-	var cfg Config
-	if err := yaml.Unmarshal(b, &cfg); err != nil {
+	cfg := Default()
+
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		var te *yaml.TypeError
+		if !errors.As(err, &te) {
+			return nil, err
+		}
+		// остальные поля yaml.v3 уже разобрал
+		for _, e := range te.Errors {
+			cfg.decodeErrors = append(cfg.decodeErrors, unknownFieldRe.ReplaceAllString(e, "неизвестный ключ $1"))
+		}
+	}
+
+	if err := applyEnv(cfg, os.LookupEnv); err != nil {
 		return nil, err
 	}
 
-	return &cfg, nil
+	return cfg, nil
+}
+
+// ChaosConfig секция chaos в виде настроек транспорта
+func (c *Config) ChaosConfig() client.ChaosConfig {
+	return client.ChaosConfig{
+		LatencyRate:     c.Chaos.LatencyRate,
+		Latency:         time.Duration(c.Chaos.LatencyMS) * time.Millisecond,
+		ErrorRate:       c.Chaos.ErrorRate,
+		RateLimitRate:   c.Chaos.RateLimitRate,
+		RetryAfter:      time.Duration(c.Chaos.RetryAfterSeconds) * time.Second,
+		ServerErrorRate: c.Chaos.ServerErrorRate,
+		ChallengeRate:   c.Chaos.ChallengeRate,
+		TruncateRate:    c.Chaos.TruncateRate,
+		Seed:            c.Chaos.Seed,
+	}
 }
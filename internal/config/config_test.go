@@ -0,0 +1,197 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, yaml string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// config.yaml из репозитория должен разбираться без неизвестных ключей
+func TestLoadRepoConfig(t *testing.T) {
+	c, err := Load("../../config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.decodeErrors) != 0 {
+		t.Errorf("ошибки разбора config.yaml: %q", c.decodeErrors)
+	}
+}
+
+func TestLoadDefaultsAndUnknownKeys(t *testing.T) {
+	path := writeConfig(t, `
+kuper:
+  store_id: 960
+  stor_id: 1
+departments:
+  names: ["Молоко"]
+http:
+  retry:
+    jiter: 0.1
+pagination:
+  per_page: много
+`)
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// незаданные поля — из Default
+	if c.Kuper.BaseURL != "https://kuper.ru" || c.Pagination.OffersLimit != 10 || c.HTTP.Retry.Jitter != 0.5 {
+		t.Errorf("значения по умолчанию потеряны: base_url=%q offers_limit=%d jitter=%v",
+			c.Kuper.BaseURL, c.Pagination.OffersLimit, c.HTTP.Retry.Jitter)
+	}
+	if c.Kuper.StoreID != 960 {
+		t.Errorf("store_id = %d", c.Kuper.StoreID)
+	}
+
+	err = c.Validate()
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("Validate = %v, ожидалась *ValidationError", err)
+	}
+	for _, want := range []string{"неизвестный ключ stor_id", "неизвестный ключ jiter", "много"} {
+		if !slices.ContainsFunc(ve.Problems, func(p string) bool { return strings.Contains(p, want) }) {
+			t.Errorf("нет ошибки %q в %q", want, ve.Problems)
+		}
+	}
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	c := Default()
+	c.Kuper.BaseURL = "kuper.ru"
+	c.Kuper.StoreID = 0
+	c.Departments.Names = []string{"id:abc", " "}
+	c.Pagination.PerPage = 10
+	c.HTTP.Retry.Jitter = 2
+	c.HTTP.Cassette.Mode = "replay"
+	c.Proxy.Mode = "socks"
+	c.RateLimit.RPS = -1
+	c.CircuitBreaker.OnOpen = "wait"
+	c.Failure.Policy = "ignore"
+	c.Quality.Rules = []string{"required", "colour"}
+	c.Output.Decimal = ";"
+
+	err := c.Validate()
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("Validate = %v, ожидалась *ValidationError", err)
+	}
+	fields := []string{
+		"kuper.base_url", "kuper.store_id", "departments.names[0]", "departments.names[1]", "pagination.per_page",
+		"http.retry.jitter", "http.cassette.path", "proxy.mode", "rate_limit.rps", "circuit_breaker.on_open",
+		"failure.policy", "quality.rules", "output.decimal",
+	}
+	for _, f := range fields {
+		if !slices.ContainsFunc(ve.Problems, func(p string) bool { return strings.HasPrefix(p, f+":") }) {
+			t.Errorf("нет ошибки поля %s", f)
+		}
+	}
+	if len(ve.Problems) != len(fields) {
+		t.Errorf("ошибок %d, ожидалось %d:\n%s", len(ve.Problems), len(fields), err)
+	}
+}
+
+func TestValidateCrawlDepartments(t *testing.T) {
+	c := Default()
+	c.Kuper.StoreID = 960
+	if err := c.Validate(); err != nil {
+		t.Errorf("Validate без отделов: %v", err)
+	}
+	if err := c.ValidateCrawl(); err == nil || !strings.Contains(err.Error(), "departments.names") {
+		t.Errorf("ValidateCrawl без отделов = %v, ожидалась ошибка departments.names", err)
+	}
+	c.Departments.All = true
+	if err := c.ValidateCrawl(); err != nil {
+		t.Errorf("ValidateCrawl с departments.all: %v", err)
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	env := map[string]string{
+		"KUPER_KUPER_STORE_ID":                     "1234",
+		"KUPER_KUPER_BASE_URL":                     "http://localhost:8081",
+		"KUPER_HTTP_RETRY_JITTER":                  "0.1",
+		"KUPER_HTTP_RETRY_STATUSES":                "[429, 503]",
+		"KUPER_DEPARTMENTS_ALL":                    "true",
+		"KUPER_DEPARTMENTS_NAMES":                  `["Молоко, сыр, яйца", "Хлеб"]`,
+		"KUPER_PROXY_LIST":                         "http://a:1, http://b:2,",
+		"KUPER_SESSION_COOKIES":                    "{region: msk, city: Одинцово}",
+		"KUPER_CIRCUIT_BREAKER_HALF_OPEN_REQUESTS": "2",
+	}
+	c := Default()
+	if err := applyEnv(c, func(k string) (string, bool) { v, ok := env[k]; return v, ok }); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"kuper.store_id", c.Kuper.StoreID, 1234},
+		{"kuper.base_url", c.Kuper.BaseURL, "http://localhost:8081"},
+		{"http.retry.jitter", c.HTTP.Retry.Jitter, 0.1},
+		{"http.retry.statuses", c.HTTP.Retry.Statuses, []int{429, 503}},
+		{"departments.all", c.Departments.All, true},
+		{"departments.names", c.Departments.Names, []string{"Молоко, сыр, яйца", "Хлеб"}},
+		{"proxy.list", c.Proxy.List, []string{"http://a:1", "http://b:2"}},
+		{"session.cookies", c.Session.Cookies, map[string]string{"region": "msk", "city": "Одинцово"}},
+		{"circuit_breaker.half_open_requests", c.CircuitBreaker.HalfOpenRequests, 2},
+		// не заданные переменные не трогают значения
+		{"pagination.per_page", c.Pagination.PerPage, 5},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s = %#v, ожидалось %#v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestApplyEnvErrors(t *testing.T) {
+	env := map[string]string{
+		"KUPER_KUPER_STORE_ID":      "магнит",
+		"KUPER_HTTP_RETRY_STATUSES": "[429,",
+	}
+	err := applyEnv(Default(), func(k string) (string, bool) { v, ok := env[k]; return v, ok })
+	if err == nil {
+		t.Fatal("ожидались ошибки разбора")
+	}
+	for name := range env {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("в ошибке нет %s: %v", name, err)
+		}
+	}
+}
+
+// переменные окружения перекрывают config.yaml
+func TestLoadEnvOverridesYAML(t *testing.T) {
+	path := writeConfig(t, "kuper:\n  store_id: 960\nproxy:\n  mode: list\n")
+	t.Setenv("KUPER_KUPER_STORE_ID", "1234")
+	t.Setenv("KUPER_PROXY_MODE", "disabled")
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Kuper.StoreID != 1234 || c.Proxy.Mode != "disabled" {
+		t.Errorf("store_id=%d proxy.mode=%q, ожидалось 1234 и disabled", c.Kuper.StoreID, c.Proxy.Mode)
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	if got := EnvName("http.retry.base_delay_ms"); got != "KUPER_HTTP_RETRY_BASE_DELAY_MS" {
+		t.Errorf("EnvName = %q", got)
+	}
+}
@@ -0,0 +1,47 @@
+package config
+
+import "slices"
+
+// Default конфиг со значениями по умолчанию; Load накладывает на него YAML и переменные окружения.
+// Незаданные здесь поля по умолчанию нулевые, что означает «выключено» или «без ограничения»
+// (ретраи, лимиты, breaker, кэш, chaos), либо значение по умолчанию выбирает сам слой транспорта
+func Default() *Config {
+	var c Config
+
+	c.Kuper.BaseURL = "https://kuper.ru"
+
+	c.Departments.MaxDistance = 3
+
+	c.Pagination.PerPage = 5 // максимум для API
+	c.Pagination.OffersLimit = 10
+
+	c.HTTP.TimeoutSeconds = 30
+	c.HTTP.Retry.Jitter = 0.5
+	c.HTTP.Cassette.Mode = "off"
+
+	c.Proxy.Mode = "disabled"
+
+	c.AntiBot.Policy = "rotate"
+
+	c.CircuitBreaker.OnOpen = "pause"
+	c.CircuitBreaker.MaxPauses = 3 // при 0 pause вёл бы себя как abort
+
+	c.Cache.Directory = "./.cache/http"
+
+	c.Failure.Policy = "fail-fast"
+	c.Failure.RetryDelayMS = 1000
+	c.Failure.MaxSkippedPages = 3
+
+	c.Quality.Rules = slices.Clone(QualityRules)
+	c.Quality.OutlierRatio = 5
+
+	c.Log.Level = "info"
+	c.Log.Format = "text"
+
+	c.Output.Directory = "./output"
+	c.Output.Format = "csv"
+	// CSV пишется через ";" для русского Excel, поэтому и дробная часть — через запятую
+	c.Output.Decimal = ","
+
+	return &c
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix префикс переменных окружения: KUPER_<СЕКЦИЯ>_<ПОЛЕ> по yaml-именам,
+// например KUPER_KUPER_STORE_ID, KUPER_HTTP_RETRY_BASE_DELAY_MS, KUPER_PROXY_MODE
+const EnvPrefix = "KUPER_"
+
+// applyEnv перекрывает поля конфига переменными окружения. Строки берутся как есть, остальные типы
+// разбираются как YAML (true, 1.5, [429, 503], {region: msk}); списки строк можно писать через запятую:
+// KUPER_PROXY_LIST=http://a:1,http://b:2, а элементы с запятыми — в виде ["Молоко, сыр", "Овощи"]
+func applyEnv(c *Config, lookup func(string) (string, bool)) error {
+	var errs []error
+	walkFields(reflect.ValueOf(c).Elem(), "", func(path string, v reflect.Value) {
+		name := EnvName(path)
+		raw, ok := lookup(name)
+		if !ok {
+			return
+		}
+		if err := setFromEnv(v, raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// EnvName имя переменной окружения для поля с yaml-путём path (например http.retry.jitter)
+func EnvName(path string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+}
+
+// walkFields обходит листовые поля структуры; path — yaml-путь через точку
+func walkFields(v reflect.Value, prefix string, fn func(path string, v reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := strings.Split(f.Tag.Get("yaml"), ",")[0]
+		if tag == "" || tag == "-" {
+			continue
+		}
+		path := tag
+		if prefix != "" {
+			path = prefix + "." + tag
+		}
+
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct {
+			walkFields(fv, path, fn)
+			continue
+		}
+		fn(path, fv)
+	}
+}
+
+func setFromEnv(v reflect.Value, raw string) error {
+	switch {
+	case v.Kind() == reflect.String:
+		v.SetString(raw)
+		return nil
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(raw), "["):
+		parts := strings.Split(raw, ",")
+		out := reflect.MakeSlice(v.Type(), 0, len(parts))
+		for _, p := range parts {
+			if p = strings.TrimSpace(p); p != "" {
+				out = reflect.Append(out, reflect.ValueOf(p))
+			}
+		}
+		v.Set(out)
+		return nil
+	}
+
+	ptr := reflect.New(v.Type())
+	if err := yaml.Unmarshal([]byte(raw), ptr.Interface()); err != nil {
+		return fmt.Errorf("не удалось разобрать %q: %w", raw, err)
+	}
+	v.Set(ptr.Elem())
+	return nil
+}
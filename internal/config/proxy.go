@@ -0,0 +1,104 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ProxyURLs итоговый список прокси: proxy.list + proxy.list_file, с подстановкой ${ENV}
+// и логином/паролем из proxy.username_env/password_env для адресов без userinfo
+func (c *Config) ProxyURLs() ([]string, error) {
+	raw := append([]string(nil), c.Proxy.List...)
+
+	if c.Proxy.ListFile != "" {
+		fromFile, err := readProxyFile(c.Proxy.ListFile)
+		if err != nil {
+			return nil, err
+		}
+		raw = append(raw, fromFile...)
+	}
+
+	out := make([]string, 0, len(raw))
+	for _, s := range raw {
+		u, err := c.resolveProxy(s)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	return out, nil
+}
+
+// RotationProxyURL proxy.rotation_url с теми же правилами подстановки, что и у списка
+func (c *Config) RotationProxyURL() (string, error) {
+	if c.Proxy.RotationURL == "" {
+		return "", nil
+	}
+	return c.resolveProxy(c.Proxy.RotationURL)
+}
+
+var envRefRe = regexp.MustCompile(`\$\{[A-Za-z_][A-Za-z0-9_]*\}`)
+
+func (c *Config) resolveProxy(s string) (string, error) {
+	// только ${NAME}: одиночный $ может встречаться в самом пароле
+	var missing []string
+	s = envRefRe.ReplaceAllStringFunc(strings.TrimSpace(s), func(ref string) string {
+		name := ref[2 : len(ref)-1]
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return v
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("прокси: не заданы переменные окружения %s", strings.Join(missing, ", "))
+	}
+
+	if c.Proxy.UsernameEnv == "" && c.Proxy.PasswordEnv == "" {
+		return s, nil
+	}
+
+	u, err := url.Parse(s)
+	if err != nil || u.User != nil {
+		// разбор адреса и его ошибки — в client.NewProxyRotator, там они маскируются
+		return s, nil
+	}
+
+	user := os.Getenv(c.Proxy.UsernameEnv)
+	if user == "" {
+		return "", fmt.Errorf("прокси: переменная окружения %s (proxy.username_env) пуста", c.Proxy.UsernameEnv)
+	}
+	if c.Proxy.PasswordEnv != "" {
+		u.User = url.UserPassword(user, os.Getenv(c.Proxy.PasswordEnv))
+	} else {
+		u.User = url.User(user)
+	}
+	return u.String(), nil
+}
+
+// readProxyFile файл секретов: по одному адресу в строке, пустые строки и # комментарии пропускаются
+func readProxyFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("proxy.list_file: %w", err)
+	}
+	defer f.Close()
+
+	var out []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		out = append(out, line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("proxy.list_file: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SelectorKind способ выбора отдела в departments.names
+type SelectorKind string
+
+const (
+	SelectName SelectorKind = "name" // точное название (без учёта регистра, ё/е и лишних пробелов), иначе — ближайшее похожее
+	SelectSlug SelectorKind = "slug" // slug:moloko-syr-yaytsa, хэш-суффикс slug'а (-c44b0ed) можно не указывать
+	SelectID   SelectorKind = "id"   // id:101
+	SelectGlob SelectorKind = "glob" // glob:*молоко* — шаблон по названию
+	SelectRe   SelectorKind = "re"   // re:^(молоко|сыр) — регулярное выражение по названию, без учёта регистра
+)
+
+// Selector разобранный элемент departments.names
+type Selector struct {
+	Raw   string
+	Kind  SelectorKind
+	Value string
+	ID    int
+	Re    *regexp.Regexp
+}
+
+// ParseSelector разбирает "slug:...", "id:...", "glob:...", "re:..."; без префикса — название отдела
+func ParseSelector(raw string) (Selector, error) {
+	s := Selector{Raw: raw, Kind: SelectName, Value: strings.TrimSpace(raw)}
+
+	prefix, rest, ok := strings.Cut(s.Value, ":")
+	if !ok {
+		return s, nil
+	}
+	rest = strings.TrimSpace(rest)
+
+	switch SelectorKind(strings.ToLower(prefix)) {
+	case SelectSlug:
+		s.Kind, s.Value = SelectSlug, strings.ToLower(rest)
+	case SelectID:
+		id, err := strconv.Atoi(rest)
+		if err != nil || id <= 0 {
+			return s, fmt.Errorf("%q: ожидается id:<число>", raw)
+		}
+		s.Kind, s.Value, s.ID = SelectID, rest, id
+	case SelectGlob:
+		// шаблон сравнивается с названием в нижнем регистре
+		pattern := strings.ToLower(rest)
+		if _, err := path.Match(pattern, ""); err != nil {
+			return s, fmt.Errorf("%q: некорректный шаблон: %v", raw, err)
+		}
+		s.Kind, s.Value = SelectGlob, pattern
+	case SelectRe:
+		re, err := regexp.Compile("(?i)" + rest)
+		if err != nil {
+			return s, fmt.Errorf("%q: некорректное регулярное выражение: %v", raw, err)
+		}
+		s.Kind, s.Value, s.Re = SelectRe, rest, re
+	default:
+		// двоеточие в обычном названии отдела
+	}
+	return s, nil
+}
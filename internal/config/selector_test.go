@@ -0,0 +1,54 @@
+package config
+
+import "testing"
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		raw   string
+		kind  SelectorKind
+		value string
+		id    int
+	}{
+		{"Молоко, сыр, яйца", SelectName, "Молоко, сыр, яйца", 0},
+		{"  Хлеб ", SelectName, "Хлеб", 0},
+		{"slug:Moloko-Syr-Yaytsa", SelectSlug, "moloko-syr-yaytsa", 0},
+		{"SLUG: napitki", SelectSlug, "napitki", 0},
+		{"id:101", SelectID, "101", 101},
+		{"glob:*Молоко*", SelectGlob, "*молоко*", 0},
+		{"re:^(овощи|фрукты)", SelectRe, "^(овощи|фрукты)", 0},
+		// двоеточие в названии отдела без известного префикса
+		{"Акции: скидки недели", SelectName, "Акции: скидки недели", 0},
+	}
+	for _, tt := range tests {
+		s, err := ParseSelector(tt.raw)
+		if err != nil {
+			t.Errorf("ParseSelector(%q): %v", tt.raw, err)
+			continue
+		}
+		if s.Raw != tt.raw || s.Kind != tt.kind || s.Value != tt.value || s.ID != tt.id {
+			t.Errorf("ParseSelector(%q) = %+v, ожидалось %s %q id=%d", tt.raw, s, tt.kind, tt.value, tt.id)
+		}
+		if (s.Kind == SelectRe) != (s.Re != nil) {
+			t.Errorf("ParseSelector(%q): Re = %v", tt.raw, s.Re)
+		}
+	}
+}
+
+func TestParseSelectorRe(t *testing.T) {
+	s, err := ParseSelector("re:^(овощи|фрукты)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// без учёта регистра
+	if !s.Re.MatchString("Овощи, фрукты, зелень") || s.Re.MatchString("Молоко") {
+		t.Errorf("re:^(овощи|фрукты) сопоставляется неверно")
+	}
+}
+
+func TestParseSelectorInvalid(t *testing.T) {
+	for _, raw := range []string{"id:abc", "id:0", "id:-5", "glob:[", "re:(овощи"} {
+		if s, err := ParseSelector(raw); err == nil {
+			t.Errorf("ParseSelector(%q) = %+v, ожидалась ошибка", raw, s)
+		}
+	}
+}
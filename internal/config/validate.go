@@ -0,0 +1,300 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"kuperparser/internal/client"
+	"kuperparser/internal/logging"
+	"kuperparser/storage"
+)
+
+// ValidationError все найденные в конфиге ошибки
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "некорректный конфиг:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+type problems []string
+
+func (p *problems) add(field, format string, args ...any) {
+	*p = append(*p, field+": "+fmt.Sprintf(format, args...))
+}
+
+func (p *problems) nonNegative(field string, v float64) {
+	if v < 0 {
+		p.add(field, "не может быть отрицательным")
+	}
+}
+
+func (p *problems) addErr(field string, err error) {
+	if err != nil {
+		p.add(field, "%v", err)
+	}
+}
+
+func (p *problems) selectors(field string, list []string) {
+	for i, n := range list {
+		f := fmt.Sprintf("%s[%d]", field, i)
+		if strings.TrimSpace(n) == "" {
+			p.add(f, "пустое название")
+			continue
+		}
+		_, err := ParseSelector(n)
+		p.addErr(f, err)
+	}
+}
+
+// QualityRules правила проверки товаров (quality.rules), см. logic.QualityRule
+var QualityRules = []string{"required", "price", "url", "duplicate", "outlier"}
+
+// Validate проверяет конфиг целиком и возвращает *ValidationError со всеми ошибками сразу.
+// Отделы не обязательны: store, categories, search и proxy-check обходятся без них
+func (c *Config) Validate() error {
+	return c.validate(false)
+}
+
+// ValidateCrawl то же, что Validate, и требует отделы для обхода: departments.names или departments.all
+func (c *Config) ValidateCrawl() error {
+	return c.validate(true)
+}
+
+func (c *Config) validate(crawl bool) error {
+	var p problems
+	p = append(p, c.decodeErrors...)
+
+	if u, err := url.Parse(c.Kuper.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		p.add("kuper.base_url", "ожидается адрес вида https://kuper.ru, получено %q", c.Kuper.BaseURL)
+	}
+	if c.Kuper.StoreID <= 0 {
+		p.add("kuper.store_id", "должен быть больше 0")
+	}
+
+	d := c.Departments
+	if crawl && len(d.Names) == 0 && !d.All {
+		p.add("departments.names", "не указано ни одного отдела (или departments.all: true)")
+	}
+	p.selectors("departments.names", d.Names)
+	p.selectors("departments.exclude", d.Exclude)
+	p.nonNegative("departments.max_distance", float64(d.MaxDistance))
+	p.nonNegative("departments.min_products", float64(d.MinProducts))
+	p.nonNegative("departments.max_products", float64(d.MaxProducts))
+	if d.MaxProducts > 0 && d.MinProducts > d.MaxProducts {
+		p.add("departments.min_products", "больше departments.max_products")
+	}
+
+	if c.Pagination.PerPage < 1 || c.Pagination.PerPage > 5 {
+		p.add("pagination.per_page", "должно быть от 1 до 5 (ограничение API), получено %d", c.Pagination.PerPage)
+	}
+	if c.Pagination.OffersLimit < 1 {
+		p.add("pagination.offers_limit", "должно быть больше 0")
+	}
+
+	c.validateHTTP(&p)
+	c.validateProxy(&p)
+
+	if c.Headers.Profile != "" || c.Headers.ProfilesFile != "" {
+		var profiles []client.HeaderProfile
+		var err error
+		if c.Headers.ProfilesFile != "" {
+			profiles, err = client.LoadHeaderProfiles(c.Headers.ProfilesFile)
+			p.addErr("headers.profiles_file", err)
+		}
+		if err == nil {
+			_, err = client.NewHeaderProfileSet(profiles, c.Headers.Profile)
+			p.addErr("headers.profile", err)
+		}
+	}
+
+	for i, raw := range c.Session.WarmupURLs {
+		if u, err := url.Parse(raw); err != nil || u.Host == "" {
+			p.add(fmt.Sprintf("session.warmup_urls[%d]", i), "некорректный адрес %q", raw)
+		}
+	}
+
+	_, err := client.ParseBlockPolicy(c.AntiBot.Policy)
+	p.addErr("antibot.policy", err)
+	p.nonNegative("antibot.cooldown_seconds", float64(c.AntiBot.CooldownSeconds))
+
+	rl := c.RateLimit
+	p.nonNegative("rate_limit.rps", rl.RPS)
+	p.nonNegative("rate_limit.burst", float64(rl.Burst))
+	p.nonNegative("rate_limit.per_proxy_rps", rl.PerProxyRPS)
+	p.nonNegative("rate_limit.per_proxy_burst", float64(rl.PerProxyBurst))
+	p.nonNegative("rate_limit.min_rps", rl.MinRPS)
+	p.nonNegative("rate_limit.recover_step", rl.RecoverStep)
+	if rl.RPS > 0 && rl.MinRPS > rl.RPS {
+		p.add("rate_limit.min_rps", "больше rate_limit.rps")
+	}
+
+	cb := c.CircuitBreaker
+	p.nonNegative("circuit_breaker.failure_threshold", float64(cb.FailureThreshold))
+	p.nonNegative("circuit_breaker.per_proxy_failure_threshold", float64(cb.PerProxyFailureThreshold))
+	p.nonNegative("circuit_breaker.open_seconds", float64(cb.OpenSeconds))
+	p.nonNegative("circuit_breaker.half_open_requests", float64(cb.HalfOpenRequests))
+	p.nonNegative("circuit_breaker.max_pauses", float64(cb.MaxPauses))
+	if cb.OnOpen != "pause" && cb.OnOpen != "abort" {
+		p.add("circuit_breaker.on_open", "ожидается pause|abort, получено %q", cb.OnOpen)
+	}
+
+	if c.Cache.Enabled {
+		if c.Cache.Directory == "" {
+			p.add("cache.directory", "не указана директория кэша")
+		}
+		if c.Cache.TTLSeconds <= 0 {
+			p.add("cache.ttl_seconds", "должно быть больше 0 при включённом кэше")
+		}
+	}
+	p.nonNegative("cache.max_mb", float64(c.Cache.MaxMB))
+
+	p.addErr("chaos", c.ChaosConfig().Validate())
+
+	f := c.Failure
+	switch f.Policy {
+	case "fail-fast", "skip-department", "skip-page":
+	default:
+		p.add("failure.policy", "ожидается fail-fast|skip-department|skip-page, получено %q", f.Policy)
+	}
+	p.nonNegative("failure.page_retries", float64(f.PageRetries))
+	p.nonNegative("failure.retry_delay_ms", float64(f.RetryDelayMS))
+	p.nonNegative("failure.max_skipped_pages", float64(f.MaxSkippedPages))
+	p.nonNegative("failure.max_errors", float64(f.MaxErrors))
+
+	q := c.Quality
+	for _, r := range q.Rules {
+		if !slices.Contains(QualityRules, r) {
+			p.add("quality.rules", "неизвестное правило %q (доступны: %s)", r, strings.Join(QualityRules, ", "))
+		}
+	}
+	if q.MaxInvalidRatio < 0 || q.MaxInvalidRatio > 1 {
+		p.add("quality.max_invalid_ratio", "должно быть от 0 до 1")
+	}
+	if slices.Contains(q.Rules, "outlier") && q.OutlierRatio <= 1 {
+		p.add("quality.outlier_ratio", "должно быть больше 1 при включённом правиле outlier")
+	}
+
+	p.nonNegative("concurrency.workers", float64(c.Concurrency.Workers))
+
+	if _, err := logging.New(io.Discard, c.Log.Level, c.Log.Format); err != nil {
+		p.add("log", "%v", err)
+	}
+
+	if c.Output.Directory == "" {
+		p.add("output.directory", "не указана директория")
+	}
+	_, err = storage.Ext(c.Output.Format)
+	p.addErr("output.format", err)
+	if c.Output.Decimal != "." && c.Output.Decimal != "," {
+		p.add("output.decimal", "ожидается \".\" или \",\", получено %q", c.Output.Decimal)
+	}
+
+	if len(p) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: p}
+}
+
+func (c *Config) validateHTTP(p *problems) {
+	h := c.HTTP
+	if h.TimeoutSeconds <= 0 {
+		p.add("http.timeout_seconds", "должно быть больше 0")
+	}
+	p.nonNegative("http.retries", float64(h.Retries))
+
+	for _, s := range h.Retry.Statuses {
+		if s < 100 || s > 599 {
+			p.add("http.retry.statuses", "некорректный HTTP статус %d", s)
+		}
+	}
+	_, err := client.ParseErrorClasses(h.Retry.Errors)
+	p.addErr("http.retry.errors", err)
+	if h.Retry.Jitter < 0 || h.Retry.Jitter > 1 {
+		p.add("http.retry.jitter", "должно быть от 0 до 1")
+	}
+	p.nonNegative("http.retry.base_delay_ms", float64(h.Retry.BaseDelayMS))
+	p.nonNegative("http.retry.max_delay_ms", float64(h.Retry.MaxDelayMS))
+	p.nonNegative("http.retry.max_elapsed_seconds", float64(h.Retry.MaxElapsedSeconds))
+	p.nonNegative("http.retry.max_retry_after_seconds", float64(h.Retry.MaxRetryAfterSeconds))
+	p.nonNegative("http.retry.budget_ratio", h.Retry.BudgetRatio)
+
+	switch client.CassetteMode(h.Cassette.Mode) {
+	case client.CassetteOff, "":
+	case client.CassetteRecord, client.CassetteReplay:
+		if h.Cassette.Path == "" {
+			p.add("http.cassette.path", "обязателен в режиме %s", h.Cassette.Mode)
+		}
+	default:
+		p.add("http.cassette.mode", "ожидается off|record|replay, получено %q", h.Cassette.Mode)
+	}
+
+	known := client.RegisteredMiddlewares()
+	for _, name := range h.Middleware {
+		if !slices.Contains(known, name) {
+			p.add("http.middleware", "неизвестный слой %q (доступны: %s)", name, strings.Join(known, ", "))
+		}
+	}
+}
+
+func (c *Config) validateProxy(p *problems) {
+	switch client.ProxyMode(c.Proxy.Mode) {
+	case client.ProxyDisabled, "":
+	case client.ProxyList:
+		list, err := c.ProxyURLs()
+		if err != nil {
+			p.addErr("proxy.list", err)
+			return
+		}
+		if len(list) == 0 {
+			p.add("proxy.list", "пустой список прокси в режиме list")
+		}
+		for i, raw := range list {
+			if msg := checkProxyURL(raw); msg != "" {
+				p.add(fmt.Sprintf("proxy.list[%d]", i), "%s", msg)
+			}
+		}
+	case client.ProxyRotation:
+		u, err := c.RotationProxyURL()
+		switch {
+		case err != nil:
+			p.addErr("proxy.rotation_url", err)
+		case u == "":
+			p.add("proxy.rotation_url", "обязателен в режиме rotation")
+		default:
+			if msg := checkProxyURL(u); msg != "" {
+				p.add("proxy.rotation_url", "%s", msg)
+			}
+		}
+	default:
+		p.add("proxy.mode", "ожидается disabled|list|rotation, получено %q", c.Proxy.Mode)
+	}
+}
+
+// checkProxyURL текст ошибки без логина/пароля из адреса
+func checkProxyURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err
+		}
+		// маскируется уже в кавычках: так вырезается и пароль с пробелами
+		return fmt.Sprintf("некорректный адрес прокси %s: %v", client.RedactString(strconv.Quote(raw)), err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return fmt.Sprintf("неподдерживаемая схема прокси %q в %q", u.Scheme, client.RedactURL(u))
+	}
+	if u.Hostname() == "" || u.Port() == "" {
+		return fmt.Sprintf("в адресе прокси %q нужны хост и порт", client.RedactURL(u))
+	}
+	return ""
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+
+	"kuperparser/internal/client"
 )
 
 type Category struct {
@@ -33,6 +35,7 @@ func (s *service) ListCategories(ctx context.Context, storeID int) ([]Category,
 	}
 
 	s.applyDefaultHeaders(req)
+	s.log(ctx).Debug("запрос к API", "endpoint", "ListCategories", "store_id", storeID)
 
 	resp, err := s.transport.Do(req)
 	if err != nil {
@@ -43,13 +46,21 @@ func (s *service) ListCategories(ctx context.Context, storeID int) ([]Category,
 	// доп проверка для отладки если вернуло не 200 резульатат
 	if resp.StatusCode != http.StatusOK {
 		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
-		return nil, fmt.Errorf("ListCategories: статус=%d body=%s", resp.StatusCode, string(b))
+		return nil, newAPIError("ListCategories", url, resp.StatusCode, b)
 	}
 
-	var out categoriesResp
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
 		return nil, err
 	}
 
+	var out categoriesResp
+	if err := json.Unmarshal(body, &out); err != nil {
+		if reason, ok := client.BlockedBody(resp.Header.Get("Content-Type"), body, true); ok {
+			return nil, &client.BlockedError{URL: url, Status: resp.StatusCode, Reason: reason}
+		}
+		return nil, schemaError("ListCategories", err, body)
+	}
+
 	return out.Categories, nil
 }
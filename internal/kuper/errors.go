@@ -0,0 +1,105 @@
+package kuper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrNotFound    = errors.New("kuper: не найдено")
+	ErrRateLimited = errors.New("kuper: превышен лимит запросов")
+	ErrSchema      = errors.New("kuper: неожиданный формат ответа")
+)
+
+// APIError ответ API с ошибкой: не-200 статус или поле code в теле
+type APIError struct {
+	Endpoint string // ListCategories | GetStore | ListProducts
+	URL      string
+	Status   int
+	Code     string
+	Message  string
+	Body     string // начало тела ответа для отладки
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: статус=%d", e.Endpoint, e.Status)
+	if e.Code != "" {
+		fmt.Fprintf(&b, " code=%s", e.Code)
+	}
+	if e.Message != "" {
+		fmt.Fprintf(&b, " message=%s", e.Message)
+	}
+	if e.URL != "" {
+		fmt.Fprintf(&b, " url=%s", e.URL)
+	}
+	if e.Message == "" && e.Body != "" {
+		fmt.Fprintf(&b, " body=%s", e.Body)
+	}
+	return b.String()
+}
+
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.Status == http.StatusNotFound || strings.Contains(strings.ToLower(e.Code), "not_found")
+	case ErrRateLimited:
+		return e.Status == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// SchemaError ответ 200, но его структура не совпадает с ожидаемой
+type SchemaError struct {
+	Endpoint string
+	Err      error
+	Body     string
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("%s: неожиданный формат ответа: %v, body=%s", e.Endpoint, e.Err, e.Body)
+}
+
+func (e *SchemaError) Is(target error) bool { return target == ErrSchema }
+func (e *SchemaError) Unwrap() error        { return e.Err }
+
+// newAPIError собирает APIError из ответа, вытаскивая code/message из JSON тела, если они есть
+func newAPIError(endpoint, url string, status int, body []byte) *APIError {
+	e := &APIError{
+		Endpoint: endpoint,
+		URL:      url,
+		Status:   status,
+		Body:     string(body[:min(len(body), 4096)]),
+	}
+
+	var raw map[string]any
+	if json.Unmarshal(body, &raw) == nil {
+		e.Code, e.Message = apiErrorFields(raw)
+	}
+	return e
+}
+
+// apiErrorFields code/message из {"code":..,"message":..} или {"errors":[{...}]}
+func apiErrorFields(raw map[string]any) (code, message string) {
+	if c, ok := raw["code"]; ok && c != nil {
+		code = fmt.Sprint(c)
+	}
+	if m, ok := raw["message"].(string); ok {
+		message = m
+	}
+	if code == "" && message == "" {
+		if errs, ok := raw["errors"].([]any); ok && len(errs) > 0 {
+			if m, ok := errs[0].(map[string]any); ok {
+				return apiErrorFields(m)
+			}
+		}
+	}
+	return code, message
+}
+
+func schemaError(endpoint string, err error, body []byte) *SchemaError {
+	return &SchemaError{Endpoint: endpoint, Err: err, Body: string(body[:min(len(body), 1024)])}
+}
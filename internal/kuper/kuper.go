@@ -2,11 +2,17 @@ package kuper
 
 import (
 	"context"
+	"log/slog"
 	"net/http"
+	"strings"
 
 	"kuperparser/internal/client"
+	"kuperparser/internal/logging"
 )
 
+// ErrBlocked запрос упёрся в антибот/капчу (тот же sentinel, что и в client)
+var ErrBlocked = client.ErrBlocked
+
 type KuperService interface {
 	ListCategories(ctx context.Context, storeID int) ([]Category, error)
 
@@ -18,13 +24,38 @@ type KuperService interface {
 type service struct {
 	transport client.Transport
 	baseURL   string
+	logger    *slog.Logger
+}
+
+type Option func(*service)
+
+// WithLogger логгер сервиса; поля из логгера в контексте запроса имеют приоритет
+func WithLogger(l *slog.Logger) Option {
+	return func(s *service) { s.logger = l }
+}
+
+// WithBaseURL адрес API вместо https://kuper.ru, например локальный cmd/kupermock
+func WithBaseURL(u string) Option {
+	return func(s *service) {
+		if u = strings.TrimRight(u, "/"); u != "" {
+			s.baseURL = u
+		}
+	}
 }
 
-func NewKuperService(transport client.Transport) KuperService {
-	return &service{
+func NewKuperService(transport client.Transport, opts ...Option) KuperService {
+	s := &service{
 		transport: transport,
 		baseURL:   "https://kuper.ru",
 	}
+	for _, o := range opts {
+		o(s)
+	}
+	return s
+}
+
+func (s *service) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, s.logger)
 }
 
 func (s *service) applyDefaultHeaders(req *http.Request) {
@@ -37,8 +68,8 @@ func (s *service) applyDefaultHeaders(req *http.Request) {
 
 	req.Header.Set("Accept", "application/json, text/plain, */*")
 	req.Header.Set("Accept-Language", "ru-RU,ru;q=0.9,en;q=0.8")
-	req.Header.Set("Referer", "https://kuper.ru/")
-	req.Header.Set("Origin", "https://kuper.ru")
+	req.Header.Set("Referer", s.baseURL+"/")
+	req.Header.Set("Origin", s.baseURL)
 
 	req.Header.Set("Sec-Fetch-Site", "same-origin")
 	req.Header.Set("Sec-Fetch-Mode", "cors")
@@ -0,0 +1,77 @@
+package kuper
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"kuperparser/internal/client"
+)
+
+// testdata/kupermock.json записана через http.cassette.mode: record с cmd/kupermock
+func replayService(t *testing.T) KuperService {
+	t.Helper()
+	cas, err := client.LoadCassette("testdata/kupermock.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cas.Interactions) == 0 {
+		t.Fatal("пустая кассета testdata/kupermock.json")
+	}
+	// хост в кассете не сравнивается: запросы сопоставляются по методу, пути и query
+	return NewKuperService(&client.ReplayTransport{Cassette: cas})
+}
+
+func TestGetStore(t *testing.T) {
+	svc := replayService(t)
+
+	got, err := svc.GetStore(context.Background(), 960)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := StoreInfo{
+		StoreID:      960,
+		StoreName:    "Магнит",
+		StoreAddress: "Московская область, Одинцово, Можайское шоссе, 119Б",
+		RetailerName: "Магнит",
+	}
+	if got != want {
+		t.Errorf("GetStore = %+v, ожидалось %+v", got, want)
+	}
+}
+
+func TestListProducts(t *testing.T) {
+	svc := replayService(t)
+
+	prods, err := svc.ListProducts(context.Background(), 960, "moloko-syr-yaytsa", 1, 5, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prods) != 5 {
+		t.Fatalf("товаров %d, ожидалось 5", len(prods))
+	}
+
+	// товары из departments[] получают название подотдела
+	wantDeps := []string{"Молоко", "Молоко", "Молоко", "Сыр", "Сыр"}
+	for i, p := range prods {
+		if p.Department != wantDeps[i] {
+			t.Errorf("товар %d: подотдел %q, ожидался %q", i, p.Department, wantDeps[i])
+		}
+	}
+	if name, _ := prods[0].Raw["name"].(string); name != "Молоко Простоквашино пастеризованное 3,2%, 930 мл" {
+		t.Errorf("название первого товара %q", name)
+	}
+}
+
+func TestListProductsNotFound(t *testing.T) {
+	svc := replayService(t)
+
+	_, err := svc.ListProducts(context.Background(), 960, "net-takogo", 1, 5, 10)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("ошибка %v, ожидалась ErrNotFound", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != 404 || apiErr.Code != "department_not_found" {
+		t.Errorf("ошибка %#v, ожидалась APIError 404 department_not_found", err)
+	}
+}
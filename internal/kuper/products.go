@@ -6,10 +6,15 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+
+	"kuperparser/internal/client"
 )
 
 type Product struct {
 	Raw map[string]any
+
+	// Department подотдел, если ответ сгруппирован по departments[] (departments[].name); иначе пусто
+	Department string
 }
 
 type productsResp struct {
@@ -33,6 +38,7 @@ func (s *service) ListProducts(ctx context.Context, storeID int, departmentSlug
 		return nil, err
 	}
 	s.applyDefaultHeaders(req)
+	s.log(ctx).Debug("запрос к API", "endpoint", "ListProducts", "store_id", storeID, "slug", departmentSlug, "page", page)
 
 	resp, err := s.transport.Do(req)
 	if err != nil {
@@ -46,30 +52,32 @@ func (s *service) ListProducts(ctx context.Context, storeID int, departmentSlug
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf(
-			"ListProducts: статус=%d url=%s body=%s",
-			resp.StatusCode,
-			url,
-			string(bodyBytes[:min(len(bodyBytes), 4096)]),
-		)
+		return nil, newAPIError("ListProducts", url, resp.StatusCode, bodyBytes)
 	}
 
 	// парсинг структуры json файла с товаром
 	var raw map[string]any
 	if err := json.Unmarshal(bodyBytes, &raw); err != nil {
-		return nil, fmt.Errorf("ListProducts: не удалось распарсить JSON, body=%s", string(bodyBytes[:min(len(bodyBytes), 1024)]))
+		if reason, ok := client.BlockedBody(resp.Header.Get("Content-Type"), bodyBytes, true); ok {
+			return nil, &client.BlockedError{URL: url, Status: resp.StatusCode, Reason: reason}
+		}
+		return nil, schemaError("ListProducts", err, bodyBytes)
 	}
 	if deps, ok := raw["departments"].([]any); ok {
-		var all []any
+		var all []Product
 		for _, d := range deps {
 			if dep, ok := d.(map[string]any); ok {
 				if prods, ok := dep["products"].([]any); ok && len(prods) > 0 {
-					all = append(all, prods...)
+					name, _ := dep["name"].(string)
+					for _, p := range toProducts(prods) {
+						p.Department = name
+						all = append(all, p)
+					}
 				}
 			}
 		}
 		if len(all) > 0 {
-			return toProducts(all), nil
+			return all, nil
 		}
 	}
 	// проверки на пустые массивы в полученном json
@@ -90,9 +98,8 @@ func (s *service) ListProducts(ctx context.Context, storeID int, departmentSlug
 		}
 	}
 
-	if code, ok := raw["code"]; ok {
-		msg, _ := raw["message"].(string)
-		return nil, fmt.Errorf("ListProducts: api error code=%v message=%s", code, msg)
+	if _, ok := raw["code"]; ok {
+		return nil, newAPIError("ListProducts", url, resp.StatusCode, bodyBytes)
 	}
 	return []Product{}, nil
 
@@ -6,13 +6,15 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+
+	"kuperparser/internal/client"
 )
 
 type StoreInfo struct {
-	StoreID      int
-	StoreName    string
-	StoreAddress string
-	RetailerName string
+	StoreID      int    `json:"store_id"`
+	StoreName    string `json:"store_name"`
+	StoreAddress string `json:"store_address"`
+	RetailerName string `json:"retailer_name"`
 }
 
 type storeResp struct {
@@ -40,6 +42,7 @@ func (s *service) GetStore(ctx context.Context, storeID int) (StoreInfo, error)
 		return StoreInfo{}, err
 	}
 	s.applyDefaultHeaders(req)
+	s.log(ctx).Debug("запрос к API", "endpoint", "GetStore", "store_id", storeID)
 
 	resp, err := s.transport.Do(req)
 	if err != nil {
@@ -49,14 +52,25 @@ func (s *service) GetStore(ctx context.Context, storeID int) (StoreInfo, error)
 
 	if resp.StatusCode != http.StatusOK {
 		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
-		return StoreInfo{}, fmt.Errorf("GetStore: статус=%d body=%s", resp.StatusCode, string(b))
+		return StoreInfo{}, newAPIError("GetStore", url, resp.StatusCode, b)
 	}
 
-	var out storeResp
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
 		return StoreInfo{}, err
 	}
 
+	var out storeResp
+	if err := json.Unmarshal(body, &out); err != nil {
+		if reason, ok := client.BlockedBody(resp.Header.Get("Content-Type"), body, true); ok {
+			return StoreInfo{}, &client.BlockedError{URL: url, Status: resp.StatusCode, Reason: reason}
+		}
+		return StoreInfo{}, schemaError("GetStore", err, body)
+	}
+	if out.Store.ID == 0 {
+		return StoreInfo{}, schemaError("GetStore", fmt.Errorf("нет поля store.id"), body)
+	}
+
 	addr := out.Store.Location.FullAddress
 	if addr == "" {
 		addr = fmt.Sprintf("%s, %s %s", out.Store.Location.City, out.Store.Location.Street, out.Store.Location.Building)
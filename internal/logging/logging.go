@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// New создаёт логгер: level debug|info|warn|error, format text|json
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	switch strings.ToLower(level) {
+	case "debug":
+		lvl = slog.LevelDebug
+	case "info", "":
+		lvl = slog.LevelInfo
+	case "warn", "warning":
+		lvl = slog.LevelWarn
+	case "error":
+		lvl = slog.LevelError
+	default:
+		return nil, fmt.Errorf("неизвестный log.level=%q (ожидается debug|info|warn|error)", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	switch strings.ToLower(format) {
+	case "text", "":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("неизвестный log.format=%q (ожидается text|json)", format)
+	}
+}
+
+// NewRunID короткий случайный идентификатор запуска
+func NewRunID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type ctxKey struct{}
+
+// NewContext кладёт логгер (с полями run_id/store_id/slug/page) в контекст запроса,
+// чтобы транспортные слои логировали с теми же полями. Поля записей во всех пакетах:
+// run_id, store_id, slug, page — запуск и место обхода; url — адрес запроса (без логина/пароля),
+// proxy, status, attempt, err — транспорт; path — файл на диске
+func NewContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// Lookup логгер из контекста, если он туда был положен
+func Lookup(ctx context.Context) (*slog.Logger, bool) {
+	l, ok := ctx.Value(ctxKey{}).(*slog.Logger)
+	return l, ok && l != nil
+}
+
+// FromContext логгер из контекста, иначе fallback, иначе slog.Default()
+func FromContext(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if l, ok := Lookup(ctx); ok {
+		return l
+	}
+	if fallback != nil {
+		return fallback
+	}
+	return slog.Default()
+}
@@ -0,0 +1,155 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"kuperparser/internal/client"
+	"kuperparser/internal/config"
+	"kuperparser/internal/kuper"
+	"kuperparser/internal/logging"
+)
+
+// Validate проверяет конфиг для обхода (config.ValidateCrawl) и собирает транспорт без запросов к сайту
+func Validate(ctx context.Context, cfg *config.Config) error {
+	if err := cfg.ValidateCrawl(); err != nil {
+		return err
+	}
+	_, _, err := NewService(ctx, cfg, nil)
+	return err
+}
+
+// Categories категории магазина kuper.store_id
+func Categories(ctx context.Context, cfg *config.Config) ([]kuper.Category, error) {
+	svc, closeSvc, err := NewService(ctx, cfg, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer closeSvc()
+
+	return svc.ListCategories(ctx, cfg.Kuper.StoreID)
+}
+
+// Store информация о магазине kuper.store_id
+func Store(ctx context.Context, cfg *config.Config) (kuper.StoreInfo, error) {
+	svc, closeSvc, err := NewService(ctx, cfg, nil)
+	if err != nil {
+		return kuper.StoreInfo{}, err
+	}
+	defer closeSvc()
+
+	return svc.GetStore(ctx, cfg.Kuper.StoreID)
+}
+
+// SearchHit товар, найденный Search
+type SearchHit struct {
+	Department string `json:"department"`
+	Name       string `json:"name"`
+	Price      string `json:"price"`
+	URL        string `json:"url"`
+}
+
+// Search ищет товары, в названии которых есть query (без учёта регистра), в отделах из конфига;
+// если отделы не заданы — во всех отделах магазина (с учётом departments.exclude и ограничений по числу товаров).
+// У API нет поиска, поэтому отделы обходятся целиком
+func Search(ctx context.Context, cfg *config.Config, query string) ([]SearchHit, error) {
+	logger := logging.FromContext(ctx, nil)
+
+	svc, closeSvc, err := NewService(ctx, cfg, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer closeSvc()
+
+	storeID := cfg.Kuper.StoreID
+	categories, err := svc.ListCategories(ctx, storeID)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить категории: %w", err)
+	}
+
+	if len(cfg.Departments.Names) == 0 {
+		c := *cfg
+		c.Departments.All = true
+		cfg = &c
+	}
+	sel, err := resolveDepartments(cfg, categories, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	needle := strings.ToLower(strings.TrimSpace(query))
+	baseURL := BaseURL(cfg)
+	perPage, offersLimit := cfg.Pagination.PerPage, cfg.Pagination.OffersLimit
+
+	var hits []SearchHit
+	for _, dep := range sel.Departments {
+		slug := dep.Slug
+		for page := 1; page <= 500; page++ {
+			prods, err := listProductsWithPause(ctx, cfg, logger, func() ([]kuper.Product, error) {
+				return svc.ListProducts(ctx, storeID, slug, page, perPage, offersLimit)
+			})
+			if errors.Is(err, kuper.ErrNotFound) {
+				break
+			}
+			if err != nil {
+				return hits, fmt.Errorf("ошибка получения товаров (slug=%s page=%d): %w", slug, page, err)
+			}
+			if len(prods) == 0 {
+				break
+			}
+
+			for _, p := range prods {
+				name := extractName(p)
+				if !strings.Contains(strings.ToLower(name), needle) {
+					continue
+				}
+				price, _ := extractPrice(p)
+				hits = append(hits, SearchHit{
+					Department: dep.Name,
+					Name:       name,
+					Price:      price.String(),
+					URL:        extractURL(baseURL, p),
+				})
+			}
+		}
+	}
+	return hits, nil
+}
+
+// CheckProxies открывает kuper.base_url через каждый прокси из конфига
+func CheckProxies(ctx context.Context, cfg *config.Config, timeout time.Duration) ([]client.ProxyCheck, error) {
+	var proxies []string
+	switch client.ProxyMode(cfg.Proxy.Mode) {
+	case client.ProxyList:
+		list, err := cfg.ProxyURLs()
+		if err != nil {
+			return nil, err
+		}
+		proxies = list
+	case client.ProxyRotation:
+		u, err := cfg.RotationProxyURL()
+		if err != nil {
+			return nil, err
+		}
+		proxies = []string{u}
+	default:
+		return nil, fmt.Errorf("proxy.mode=%q: проверять нечего (ожидается list|rotation)", cfg.Proxy.Mode)
+	}
+
+	set, err := headerProfiles(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient := client.NewHTTPClient(timeout, logging.FromContext(ctx, nil))
+	target := BaseURL(cfg) + "/"
+
+	out := make([]client.ProxyCheck, 0, len(proxies))
+	for _, p := range proxies {
+		out = append(out, client.CheckProxy(ctx, httpClient, p, target, set))
+	}
+	return out, nil
+}
@@ -0,0 +1,49 @@
+package logic
+
+import "testing"
+
+// без departments.names поиск идёт по всем отделам магазина
+func TestSearchAllDepartments(t *testing.T) {
+	cfg := replayConfig(t)
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("конфиг без отделов должен проходить Validate: %v", err)
+	}
+
+	tests := []struct {
+		query string
+		depts []string // отделы найденных товаров по порядку
+	}{
+		{"МОЛОКО", []string{"Молоко, сыр, яйца, растительные продукты", "Молоко, сыр, яйца, растительные продукты"}},
+		{"такого товара нет", nil},
+	}
+	for _, tt := range tests {
+		hits, err := Search(quietContext(), cfg, tt.query)
+		if err != nil {
+			t.Fatalf("Search(%q): %v", tt.query, err)
+		}
+		if len(hits) != len(tt.depts) {
+			t.Fatalf("Search(%q): найдено %d, ожидалось %d: %+v", tt.query, len(hits), len(tt.depts), hits)
+		}
+		for i, h := range hits {
+			if h.Department != tt.depts[i] || h.Price == "" || h.URL == "" {
+				t.Errorf("Search(%q)[%d] = %+v", tt.query, i, h)
+			}
+		}
+	}
+	if cfg.Departments.All {
+		t.Error("Search изменил departments.all в конфиге вызывающего")
+	}
+}
+
+func TestSearchSelectedDepartments(t *testing.T) {
+	cfg := replayConfig(t)
+	cfg.Departments.Names = []string{"slug:napitki"}
+
+	hits, err := Search(quietContext(), cfg, "молоко")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 0 {
+		t.Errorf("в отделе napitki найдено %+v, ожидалось пусто", hits)
+	}
+}
@@ -0,0 +1,81 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"kuperparser/internal/client"
+	"kuperparser/internal/kuper"
+)
+
+// FailurePolicy реакция Run на ошибку отдела или страницы (failure.policy)
+type FailurePolicy string
+
+const (
+	FailFast       FailurePolicy = "fail-fast"       // первая ошибка прерывает запуск
+	SkipDepartment FailurePolicy = "skip-department" // отдел с ошибкой бросается, остальные обходятся
+	SkipPage       FailurePolicy = "skip-page"       // страница с ошибкой пропускается, обход отдела продолжается
+)
+
+// CrawlError ошибка, из-за которой пропущен отдел (Page == 0) или одна страница отдела
+type CrawlError struct {
+	Slug string
+	Page int
+	Err  error
+}
+
+func (e *CrawlError) Error() string {
+	if e.Page == 0 {
+		return fmt.Sprintf("slug=%s: %v", e.Slug, e.Err)
+	}
+	return fmt.Sprintf("slug=%s page=%d: %v", e.Slug, e.Page, e.Err)
+}
+
+func (e *CrawlError) Unwrap() error { return e.Err }
+
+// PartialError обход завершён, но часть отделов или страниц пропущена из-за ошибок
+type PartialError struct {
+	Errors []*CrawlError
+}
+
+func (e *PartialError) Error() string {
+	lines := make([]string, 0, len(e.Errors))
+	for _, ce := range e.Errors {
+		lines = append(lines, "  - "+ce.Error())
+	}
+	return fmt.Sprintf("пропущено из-за ошибок: %d\n%s", len(e.Errors), strings.Join(lines, "\n"))
+}
+
+func (e *PartialError) Unwrap() []error {
+	out := make([]error, 0, len(e.Errors))
+	for _, ce := range e.Errors {
+		out = append(out, ce)
+	}
+	return out
+}
+
+// isFatal ошибки, после которых продолжать обход бессмысленно при любой политике:
+// отмена запуска, блокировка антиботом, circuit breaker с on_open=abort.
+// Отмена определяется по ctx запуска, а не по цепочке ошибки: таймаут http.Client (http.timeout_seconds)
+// тоже удовлетворяет errors.Is(err, context.DeadlineExceeded), но это ошибка одной страницы
+func isFatal(ctx context.Context, err error) bool {
+	var openErr *client.CircuitOpenError
+	return ctx.Err() != nil ||
+		errors.Is(err, kuper.ErrBlocked) ||
+		errors.As(err, &openErr)
+}
+
+// describeFetchError поясняет ошибку получения страницы товаров
+func describeFetchError(err error) error {
+	switch {
+	case errors.Is(err, kuper.ErrBlocked):
+		return fmt.Errorf("доступ заблокирован антиботом: %w", err)
+	case errors.Is(err, kuper.ErrRateLimited):
+		return fmt.Errorf("превышен лимит запросов, ретраи не помогли: %w", err)
+	case errors.Is(err, kuper.ErrSchema):
+		return fmt.Errorf("изменился формат ответа API: %w", err)
+	}
+	return fmt.Errorf("ошибка получения товаров: %w", err)
+}
@@ -0,0 +1,41 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"kuperparser/internal/client"
+)
+
+// timeoutErr как у http.Client: net.Error с Timeout() и context.DeadlineExceeded в цепочке
+type timeoutErr struct{}
+
+func (timeoutErr) Error() string   { return "Client.Timeout exceeded while awaiting headers" }
+func (timeoutErr) Timeout() bool   { return true }
+func (timeoutErr) Temporary() bool { return true }
+func (timeoutErr) Unwrap() error   { return context.DeadlineExceeded }
+
+func TestIsFatal(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{"таймаут страницы", context.Background(), fmt.Errorf("страница 2: %w", timeoutErr{}), false},
+		{"ошибка страницы", context.Background(), errors.New("500"), false},
+		{"отмена запуска", canceled, context.Canceled, true},
+		{"антибот", context.Background(), fmt.Errorf("страница 1: %w", &client.BlockedError{Status: 403, Reason: "captcha"}), true},
+		{"breaker", context.Background(), fmt.Errorf("страница 1: %w", &client.CircuitOpenError{Key: "host=kuper.ru"}), true},
+	}
+	for _, tt := range tests {
+		if got := isFatal(tt.ctx, tt.err); got != tt.want {
+			t.Errorf("%s: isFatal = %v, ожидалось %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"kuperparser/internal/config"
+	"kuperparser/internal/metrics"
+)
+
+// crawlMetrics метрики прогресса обхода
+type crawlMetrics struct {
+	productsWritten   *metrics.CounterVec
+	pagesFetched      *metrics.CounterVec
+	crawlErrors       *metrics.CounterVec
+	qualityViolations *metrics.CounterVec
+	duration          *metrics.GaugeVec
+}
+
+func newCrawlMetrics(reg *metrics.Registry) *crawlMetrics {
+	return &crawlMetrics{
+		productsWritten: reg.NewCounterVec("kuper_products_written_total",
+			"Products written to output by store and department.", "store_id", "department"),
+		pagesFetched: reg.NewCounterVec("kuper_pages_fetched_total",
+			"Product pages fetched by store and department.", "store_id", "department"),
+		crawlErrors: reg.NewCounterVec("kuper_crawl_errors_total",
+			"Departments and pages skipped because of errors, by store and department.", "store_id", "department"),
+		qualityViolations: reg.NewCounterVec("kuper_quality_violations_total",
+			"Data quality rule violations in extracted products, by store, department and rule.", "store_id", "department", "rule"),
+		duration: reg.NewGaugeVec("kuper_crawl_duration_seconds",
+			"Duration of the last crawl run."),
+	}
+}
+
+// startMetrics поднимает /metrics (если задан metrics.listen) и возвращает функцию,
+// которая в конце запуска пишет textfile (если задан metrics.textfile) и останавливает сервер
+func startMetrics(cfg *config.Config, reg *metrics.Registry, logger *slog.Logger) (func(), error) {
+	var srv *http.Server
+
+	if addr := cfg.Metrics.Listen; addr != "" {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", reg.Handler())
+		srv = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+		go func() {
+			if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Warn("ошибка сервера метрик", "err", err)
+			}
+		}()
+		logger.Info("метрики доступны", "url", fmt.Sprintf("http://%s/metrics", ln.Addr()))
+	}
+
+	return func() {
+		if path := cfg.Metrics.Textfile; path != "" {
+			if err := reg.WriteFile(path); err != nil {
+				logger.Warn("не удалось записать метрики", "path", path, "err", err)
+			}
+		}
+		if srv != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = srv.Shutdown(ctx)
+		}
+	}, nil
+}
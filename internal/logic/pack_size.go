@@ -0,0 +1,98 @@
+package logic
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"kuperparser/internal/kuper"
+)
+
+// PackSize фасовка товара: Amount в базовых единицах Unit (кг, л, шт)
+type PackSize struct {
+	Amount float64
+	Unit   string
+	Label  string // как указано в ответе или названии: «930 мл», «6 x 0,5 л»
+}
+
+// базовые единицы цены за единицу
+const (
+	UnitKG    = "кг"
+	UnitLiter = "л"
+	UnitPiece = "шт"
+)
+
+// packUnits единица фасовки -> базовая единица и множитель
+var packUnits = map[string]struct {
+	base string
+	mul  float64
+}{
+	"мл": {UnitLiter, 0.001}, "ml": {UnitLiter, 0.001},
+	"л": {UnitLiter, 1}, "l": {UnitLiter, 1}, "liter": {UnitLiter, 1},
+	"г": {UnitKG, 0.001}, "гр": {UnitKG, 0.001}, "g": {UnitKG, 0.001}, "gram": {UnitKG, 0.001},
+	"кг": {UnitKG, 1}, "kg": {UnitKG, 1},
+	"шт": {UnitPiece, 1}, "пак": {UnitPiece, 1}, "pcs": {UnitPiece, 1}, "piece": {UnitPiece, 1},
+}
+
+// packRe «930 мл», «1,5 л», «10 шт», «6 x 0,5 л», «0,45 л x 4»; число не должно быть частью слова или другого числа («3,2%» не подходит)
+var packRe = regexp.MustCompile(`(?i)(?:^|[^\p{L}\d.,])(?:(\d+)\s*[xх×*]\s*)?(\d+(?:[.,]\d+)?)\s*(мл|л|гр|г|кг|шт|пак)\.?(?:\s*[xх×*]\s*(\d+))?(?:[^\p{L}\d]|$)`)
+
+// extractPackSize фасовка из полей ответа (human_volume, volume + volume_type), иначе из названия;
+// поля встречаются не во всех ответах API, поэтому название — основной источник
+func extractPackSize(p kuper.Product, name string) (PackSize, bool) {
+	if v, ok := asString(p.Raw["human_volume"]); ok {
+		if ps, ok := parsePackSize(v); ok {
+			return ps, true
+		}
+	}
+
+	if vt, ok := asString(p.Raw["volume_type"]); ok {
+		if v, ok := asNumberString(p.Raw["volume"]); ok {
+			if ps, ok := newPackSize(v, vt, 1, v+" "+vt); ok {
+				if n, ok := asNumberString(p.Raw["items_per_pack"]); ok {
+					if k, err := strconv.ParseFloat(n, 64); err == nil && k > 1 {
+						ps.Amount *= k
+						ps.Label = n + " x " + ps.Label
+					}
+				}
+				return ps, true
+			}
+		}
+	}
+
+	return parsePackSize(name)
+}
+
+// parsePackSize последнее упоминание фасовки в строке: в названиях она обычно в конце
+func parsePackSize(s string) (PackSize, bool) {
+	all := packRe.FindAllStringSubmatch(s, -1)
+	for i := len(all) - 1; i >= 0; i-- {
+		m := all[i]
+		// множитель до или после объёма
+		count := 1.0
+		for _, n := range []string{m[1], m[4]} {
+			if k, err := strconv.ParseFloat(n, 64); err == nil && k > 0 {
+				count *= k
+			}
+		}
+		label := strings.TrimSpace(m[0])
+		label = strings.TrimLeft(label, " ,(")
+		label = strings.TrimRight(label, " ,.;)")
+		if ps, ok := newPackSize(m[2], m[3], count, label); ok {
+			return ps, true
+		}
+	}
+	return PackSize{}, false
+}
+
+func newPackSize(amount, unit string, count float64, label string) (PackSize, bool) {
+	u, ok := packUnits[strings.ToLower(strings.TrimSpace(unit))]
+	if !ok {
+		return PackSize{}, false
+	}
+	a, err := strconv.ParseFloat(strings.ReplaceAll(amount, ",", "."), 64)
+	if err != nil || a <= 0 {
+		return PackSize{}, false
+	}
+	return PackSize{Amount: a * count * u.mul, Unit: u.base, Label: label}, true
+}
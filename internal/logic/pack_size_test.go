@@ -0,0 +1,51 @@
+package logic
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParsePackSize(t *testing.T) {
+	tests := []struct {
+		in     string
+		amount float64
+		unit   string
+		label  string
+	}{
+		{"Молоко Простоквашино пастеризованное 3,2%, 930 мл", 0.93, UnitLiter, "930 мл"},
+		{"Сок яблочный 1,5 л", 1.5, UnitLiter, "1,5 л"},
+		{"Сыр Российский 200 г", 0.2, UnitKG, "200 г"},
+		{"Картофель мытый, 2 кг", 2, UnitKG, "2 кг"},
+		{"Яйца куриные С1, 10 шт.", 10, UnitPiece, "10 шт"},
+		{"Вода 6 x 0,5 л", 3, UnitLiter, "6 x 0,5 л"},
+		{"Пиво безалкогольное 0,45 л x 4", 1.8, UnitLiter, "0,45 л x 4"},
+		{"Колбаса (300 гр)", 0.3, UnitKG, "300 гр"},
+		// последнее упоминание: в названиях фасовка обычно в конце
+		{"Йогурт 2,5% 4 шт по 120 г", 0.12, UnitKG, "120 г"},
+	}
+	for _, tt := range tests {
+		got, ok := parsePackSize(tt.in)
+		if !ok {
+			t.Errorf("parsePackSize(%q): фасовка не найдена", tt.in)
+			continue
+		}
+		if math.Abs(got.Amount-tt.amount) > 1e-9 || got.Unit != tt.unit || got.Label != tt.label {
+			t.Errorf("parsePackSize(%q) = %+v, ожидалось {%v %s %s}", tt.in, got, tt.amount, tt.unit, tt.label)
+		}
+	}
+}
+
+func TestParsePackSizeNone(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"Молоко 3,2%",
+		"Хлеб Бородинский",
+		"Салат 5лет",     // единица — часть слова
+		"Кофе 0 г",       // нулевое количество
+		"Зубная щётка X", // нет числа
+	} {
+		if got, ok := parsePackSize(in); ok {
+			t.Errorf("parsePackSize(%q) = %+v, фасовки быть не должно", in, got)
+		}
+	}
+}
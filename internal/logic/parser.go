@@ -2,161 +2,470 @@ package logic
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"kuperparser/internal/client"
 	"kuperparser/internal/config"
 	"kuperparser/internal/kuper"
+	"kuperparser/internal/logging"
+	"kuperparser/internal/metrics"
 	"kuperparser/storage"
-	"log"
+	"log/slog"
 	"os"
+	"strconv"
 	"time"
 )
 
-func Run(ctx context.Context, cfg *config.Config) error {
-	// Настройка http клиента
-	timeout := time.Duration(cfg.HTTP.TimeoutSeconds) * time.Second
-	if timeout <= 0 {
-		timeout = 30 * time.Second
+// Run выполняет полный обход и записывает итоги (Summary) в output.directory; логгер можно передать через
+// logging.NewContext, иначе он создаётся по секции log конфига. Если по failure.policy часть отделов или страниц
+// пропущена, а остальные записаны, возвращает *PartialError; если строк с нарушениями качества больше
+// quality.max_invalid_ratio — *QualityError. Summary возвращается всегда, кроме ошибки в конфиге
+func Run(ctx context.Context, cfg *config.Config) (*Summary, error) {
+	started := time.Now()
+
+	if err := cfg.ValidateCrawl(); err != nil {
+		return nil, err
 	}
 
-	httpClient := client.NewHTTPClient(timeout)
+	logger, ok := logging.Lookup(ctx)
+	if !ok {
+		l, err := logging.New(os.Stderr, cfg.Log.Level, cfg.Log.Format)
+		if err != nil {
+			return nil, err
+		}
+		logger = l
+	}
+
+	storeID := cfg.Kuper.StoreID
+	runID := logging.NewRunID()
+	logger = logger.With("run_id", runID, "store_id", storeID)
+	ctx = logging.NewContext(ctx, logger)
+
+	summary := &Summary{RunID: runID, StoreID: storeID, StartedAt: started}
+	err := run(ctx, cfg, logger, summary)
+	summary.finish(err)
 
-	tcfg := client.TransportConfig{
-		Timeout: timeout,
-		Retries: cfg.HTTP.Retries,
-		Workers: cfg.Concurrency.Workers,
+	path := SummaryPath(cfg.Output.Directory, storeID)
+	if werr := summary.WriteFile(path); werr != nil {
+		logger.Warn("не удалось записать итоги запуска", "path", path, "err", werr)
+	} else {
+		logger.Info("итоги запуска записаны", "path", path, "status", summary.Status, "rows", summary.Rows)
 	}
+	return summary, err
+}
+
+func run(ctx context.Context, cfg *config.Config, logger *slog.Logger, summary *Summary) error {
+	ext, _ := storage.Ext(cfg.Output.Format)
+	storeID := cfg.Kuper.StoreID
 
-	// Выбор режима прокси из конфига
-	switch client.ProxyMode(cfg.Proxy.Mode) {
-	case client.ProxyDisabled, "":
-		tcfg.ProxyMode = client.ProxyDisabled
-	case client.ProxyList:
-		tcfg.ProxyMode = client.ProxyList
-		tcfg.ProxyList = cfg.Proxy.List
-	case client.ProxyRotation:
-		tcfg.ProxyMode = client.ProxyRotation
-		tcfg.RotationURL = cfg.Proxy.RotationURL
-	default:
-		return fmt.Errorf("неизвестный proxy.mode=%q (ожидается disabled|list|rotation)", cfg.Proxy.Mode)
+	reg := metrics.NewRegistry()
+	crawlStats := newCrawlMetrics(reg)
+	httpStats := client.NewMetrics(reg)
+	stopMetrics, err := startMetrics(cfg, reg, logger)
+	if err != nil {
+		return fmt.Errorf("не удалось запустить сервер метрик: %w", err)
 	}
+	defer func() {
+		crawlStats.duration.Set(time.Since(summary.StartedAt).Seconds())
+		stopMetrics()
+	}()
 
-	transport, err := client.Build(httpClient, tcfg)
+	kuperSvc, closeSvc, err := NewService(ctx, cfg, httpStats)
 	if err != nil {
-		return fmt.Errorf("ошибка сборки transport слоя: %w", err)
+		return err
 	}
-	// Создание клиента kuper
-	kuperSvc := kuper.NewKuperService(transport)
+	defer closeSvc()
+	baseURL := BaseURL(cfg)
+
 	// Загрузка списка категорий магазина и получение slug при сравнении с выбранной категорией из конфига
-	storeID := cfg.Kuper.StoreID
-	log.Printf("Получаем категории магазина store_id=%d ...", storeID)
+	logger.Info("получаем категории магазина")
 
 	categories, err := kuperSvc.ListCategories(ctx, storeID)
 	if err != nil {
 		return fmt.Errorf("не удалось получить категории: %w", err)
 	}
 
-	log.Printf("Категорий получено: %d", len(categories))
-
-	log.Println(BuildAvailableCategoriesHint(categories))
+	logger.Info("категории получены", "count", len(categories))
+	logger.Debug(BuildAvailableCategoriesHint(categories))
 
-	res := ResolveCategorySlugsByNames(cfg.Departments.Names, categories)
-
-	for _, name := range res.NotFoundNames {
-		log.Printf("WARN: Категория %q не найдена в магазине store_id=%d — пропускаю", name, storeID)
+	sel, err := resolveDepartments(cfg, categories, logger)
+	for _, m := range sel.NotFound {
+		summary.NotFound = append(summary.NotFound, m.String())
 	}
-
-	if len(res.Slugs) == 0 {
-		return fmt.Errorf(
-			"ни одна категория из конфига не найдена для store_id=%d\n%s",
-			storeID,
-			BuildAvailableCategoriesHint(categories),
-		)
+	for _, f := range sel.Skipped {
+		summary.Filtered = append(summary.Filtered, FilteredSummary{Slug: f.Category.Slug, Name: f.Category.Name, Reason: f.Reason})
 	}
-
-	log.Println("Найденные slug'и категорий:")
-	for _, slug := range res.Slugs {
-		log.Printf("- %s", slug)
+	if err != nil {
+		return err
 	}
 
+	logger.Info("найденные slug'и категорий", "slugs", sel.Slugs())
+
 	// Подготовка и сборка выходного файла
 	storeInfo, err := kuperSvc.GetStore(ctx, storeID)
 	if err != nil {
 		return fmt.Errorf("не удалось получить информацию о магазине: %w", err)
 	}
+	summary.Retailer, summary.StoreAddress = storeInfo.RetailerName, storeInfo.StoreAddress
 
 	if err := ensureDir(cfg.Output.Directory); err != nil {
 		return fmt.Errorf("не удалось создать output директорию: %w", err)
 	}
 
-	for _, slug := range res.Slugs {
+	c := &crawler{
+		cfg:     cfg,
+		svc:     kuperSvc,
+		stats:   crawlStats,
+		baseURL: baseURL,
+		policy:  FailurePolicy(cfg.Failure.Policy),
+		store:   storeInfo,
+	}
+
+	var crawlErrs []*CrawlError
+	defer func() {
+		for _, e := range crawlErrs {
+			summary.Errors = append(summary.Errors, e.Error())
+		}
+	}()
+
+	done := 0
+	for _, dep := range sel.Departments {
+		slug := dep.Slug
+		slugLog := logger.With("slug", slug)
+
 		fileName := fmt.Sprintf(
-			"%s_%s_%s.csv",
+			"%s_%s_%s.%s",
 			sanitizeFilePart(storeInfo.RetailerName),
 			sanitizeFilePart(storeInfo.StoreAddress),
 			sanitizeFilePart(slug),
+			ext,
 		)
 
 		fullPath := cfg.Output.Directory + "/" + fileName
-		log.Printf("Пишем файл: %s", fullPath)
+		slugLog.Info("пишем файл", "path", fullPath)
 
-		w, err := storage.NewCSVWriter(fullPath)
+		// отделы обходятся по очереди, так что прирост счётчиков транспорта относится к текущему отделу
+		depStarted := time.Now()
+		retries, proxyFailures := httpStats.RetriesTotal(), httpStats.ProxyFailures()
+
+		res, err := c.department(ctx, slugLog, dep, fullPath)
+
+		ds := DepartmentSummary{
+			Slug:          slug,
+			Name:          dep.Name,
+			File:          fullPath,
+			Rows:          res.rows,
+			Expected:      dep.ProductsCount,
+			Pages:         res.pages,
+			SkippedPages:  len(res.skipped),
+			InvalidRows:   res.invalid,
+			DroppedRows:   res.dropped,
+			Retries:       int(httpStats.RetriesTotal() - retries),
+			ProxyFailures: int(httpStats.ProxyFailures() - proxyFailures),
+			Duration:      time.Since(depStarted).Seconds(),
+		}
 		if err != nil {
-			return fmt.Errorf("ошибка создания csv: %w", err)
+			ds.Error = err.Error()
 		}
+		summary.Departments = append(summary.Departments, ds)
+		summary.Quality.add(res)
 
-		page := 1
-		perPage := cfg.Pagination.PerPage
-		if perPage <= 0 {
-			perPage = 5
+		crawlErrs = append(crawlErrs, res.skipped...)
+		if err != nil {
+			if c.policy == FailFast || isFatal(ctx, err) {
+				return &CrawlError{Slug: slug, Err: err}
+			}
+			crawlStats.crawlErrors.Inc(strconv.Itoa(storeID), slug)
+			crawlErrs = append(crawlErrs, &CrawlError{Slug: slug, Err: err})
+			slugLog.Error("отдел пропущен из-за ошибки", "rows", res.rows, "err", err)
+		} else {
+			done++
+			slugLog.Info("готово", "rows", res.rows, "skipped_pages", len(res.skipped), "invalid_rows", res.invalid, "dropped_rows", res.dropped)
 		}
-		if perPage > 5 {
-			log.Printf("WARN: per_page=%d больше 5, для этого API максимум 5. Ставлю 5.", perPage)
-			perPage = 5
+
+		if limit := cfg.Failure.MaxErrors; limit > 0 && len(crawlErrs) >= limit {
+			return fmt.Errorf("обход прерван: ошибок %d, предел failure.max_errors=%d: %w", len(crawlErrs), limit, joinCrawlErrors(crawlErrs))
 		}
+	}
+
+	if q, limit := summary.Quality, cfg.Quality.MaxInvalidRatio; limit > 0 && q.Checked > 0 && float64(q.Invalid)/float64(q.Checked) > limit {
+		return &QualityError{Invalid: q.Invalid, Checked: q.Checked, MaxRatio: limit}
+	}
+
+	switch {
+	case len(crawlErrs) == 0:
+		return nil
+	case done == 0:
+		return fmt.Errorf("ни один отдел не обойдён: %w", joinCrawlErrors(crawlErrs))
+	}
+	return &PartialError{Errors: crawlErrs}
+}
+
+// crawler обход отделов одного запуска Run
+type crawler struct {
+	cfg     *config.Config
+	svc     kuper.KuperService
+	stats   *crawlMetrics
+	baseURL string
+	policy  FailurePolicy
+	store   kuper.StoreInfo
+}
 
-		offersLimit := cfg.Pagination.OffersLimit
-		if offersLimit <= 0 {
-			offersLimit = 10
+// deptResult итоги обхода отдела: записанные строки, полученные и пропущенные (skip-page) страницы,
+// проверенные, некорректные и отброшенные (quality.drop_invalid) строки
+type deptResult struct {
+	rows, pages               int
+	skipped                   []*CrawlError
+	checked, invalid, dropped int
+	violations                []Violation
+}
+
+// department пишет товары отдела в path; ошибка означает, что обход отдела брошен
+func (c *crawler) department(ctx context.Context, logger *slog.Logger, dep kuper.Category, path string) (deptResult, error) {
+	slug := dep.Slug
+
+	// файл прошлого запуска сейчас будет перезаписан — цены из него нужны правилу outlier
+	prev, err := storage.ReadFile(c.cfg.Output.Format, path)
+	if err != nil {
+		logger.Warn("не удалось прочитать файл прошлого запуска, цены не сравниваются", "path", path, "err", err)
+		prev = nil
+	}
+	qc := newQualityCheck(c.cfg, slug, prev)
+
+	w, err := storage.NewWriter(c.cfg.Output.Format, path, storage.Options{Decimal: c.cfg.Output.Decimal})
+	if err != nil {
+		return deptResult{}, fmt.Errorf("ошибка создания файла: %w", err)
+	}
+
+	res, err := c.pages(ctx, logger, dep, w, qc)
+	if cerr := w.Close(); cerr != nil && err == nil {
+		err = fmt.Errorf("ошибка записи файла: %w", cerr)
+	}
+	return res, err
+}
+
+// pages постранично пишет товары отдела в w
+func (c *crawler) pages(ctx context.Context, logger *slog.Logger, dep kuper.Category, w storage.Writer, qc *qualityCheck) (deptResult, error) {
+	slug := dep.Slug
+	storeLabel := strconv.Itoa(c.cfg.Kuper.StoreID)
+	src := storage.Source{
+		StoreID:        c.cfg.Kuper.StoreID,
+		Retailer:       c.store.RetailerName,
+		DepartmentSlug: slug,
+		DepartmentName: dep.Name,
+	}
+
+	var res deptResult
+	skippedInRow := 0
+	// после пропущенной страницы (skip-page) место в выдаче неизвестно: сколько товаров было на ней, не знаем
+	position := 0
+	for page := 1; ; page++ {
+		if page > 500 {
+			logger.Warn("достигнут лимит страниц, останавливаемся")
+			break
 		}
+		pageLog := logger.With("page", page)
+		pageCtx := logging.NewContext(ctx, pageLog)
 
-		total := 0
-		for {
-			prods, err := kuperSvc.ListProducts(ctx, storeID, slug, page, perPage, offersLimit)
-			if err != nil {
-				_ = w.Close()
-				return fmt.Errorf("ошибка получения товаров (slug=%s page=%d): %w", slug, page, err)
+		prods, err := c.fetchPage(pageCtx, pageLog, slug, page)
+		if errors.Is(err, kuper.ErrNotFound) {
+			// отдел пропал из магазина между получением категорий и обходом — остальные отделы не трогаем
+			logger.Warn("отдел не найден, пропускаю", "err", err)
+			break
+		}
+		if err != nil {
+			err = describeFetchError(err)
+			if c.policy != SkipPage || isFatal(ctx, err) {
+				return res, fmt.Errorf("page=%d: %w", page, err)
 			}
 
-			if len(prods) == 0 {
-				break
+			c.stats.crawlErrors.Inc(storeLabel, slug)
+			res.skipped = append(res.skipped, &CrawlError{Slug: slug, Page: page, Err: err})
+			pageLog.Error("страница пропущена из-за ошибки", "err", err)
+
+			skippedInRow++
+			position = -1
+			if limit := c.cfg.Failure.MaxSkippedPages; limit > 0 && skippedInRow >= limit {
+				return res, fmt.Errorf("пропущено страниц подряд: %d (failure.max_skipped_pages), отдел брошен", skippedInRow)
 			}
+			continue
+		}
+		skippedInRow = 0
+
+		res.pages++
+		c.stats.pagesFetched.Inc(storeLabel, slug)
+		src.Page, src.ScrapedAt = page, time.Now().Truncate(time.Second)
+
+		if len(prods) == 0 {
+			break
+		}
 
-			for _, p := range prods {
-				name := extractName(p)
-				price := extractPrice(p)
-				url := extractURL(cfg.Kuper.BaseURL, p)
+		for _, p := range prods {
+			row := extractRow(c.baseURL, p)
+			// позиция считается и для отброшенных строк: это место товара на полке, а не номер строки в файле;
+			// после пропущенной страницы позиция пустая
+			if position >= 0 {
+				position++
+			}
+			src.Position = max(position, 0)
+			src.SubDepartment = p.Department
+			row.Source = src
 
-				if err := w.WriteRow(name, price, url); err != nil {
-					_ = w.Close()
-					return fmt.Errorf("ошибка записи csv: %w", err)
+			res.checked++
+			invalid := false
+			for _, v := range qc.check(p, row, page) {
+				c.stats.qualityViolations.Inc(storeLabel, slug, string(v.Rule))
+				res.violations = append(res.violations, v)
+				pageLog.Debug("нарушение качества данных", "rule", v.Rule, "name", v.Name, "message", v.Message)
+				invalid = invalid || v.invalidates()
+			}
+			if invalid {
+				res.invalid++
+				if c.cfg.Quality.DropInvalid {
+					res.dropped++
+					continue
 				}
-				total++
 			}
 
-			page++
-			if page > 500 {
-				log.Printf("WARN: достигнут лимит страниц для slug=%s, останавливаемся", slug)
-				break
+			if err := w.Write(row); err != nil {
+				return res, fmt.Errorf("ошибка записи файла: %w", err)
 			}
+			res.rows++
+			c.stats.productsWritten.Inc(storeLabel, slug)
 		}
+	}
 
-		_ = w.Close()
-		log.Printf("Готово: slug=%s, строк=%d", slug, total)
+	return res, nil
+}
+
+// fetchPage страница товаров с failure.page_retries повторами; NotFound и фатальные ошибки не повторяются
+func (c *crawler) fetchPage(ctx context.Context, logger *slog.Logger, slug string, page int) ([]kuper.Product, error) {
+	f := c.cfg.Failure
+	for attempt := 1; ; attempt++ {
+		prods, err := listProductsWithPause(ctx, c.cfg, logger, func() ([]kuper.Product, error) {
+			return c.svc.ListProducts(ctx, c.cfg.Kuper.StoreID, slug, page, c.cfg.Pagination.PerPage, c.cfg.Pagination.OffersLimit)
+		})
+		if err == nil || errors.Is(err, kuper.ErrNotFound) || isFatal(ctx, err) || attempt > f.PageRetries {
+			return prods, err
+		}
+
+		delay := time.Duration(attempt*f.RetryDelayMS) * time.Millisecond
+		logger.Warn("ошибка страницы, повтор", "attempt", attempt, "max", f.PageRetries, "delay", delay, "err", err)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
 	}
+}
 
-	return nil
+// joinCrawlErrors все ошибки обхода одной ошибкой
+func joinCrawlErrors(errs []*CrawlError) error {
+	return errors.Join((&PartialError{Errors: errs}).Unwrap()...)
+}
+
+// departmentSelection отделы для обхода и отброшенные при выборе
+type departmentSelection struct {
+	Departments []kuper.Category
+	NotFound    []CategoryMiss
+	Skipped     []SkippedDepartment
+}
 
+func (s departmentSelection) Slugs() []string {
+	slugs := make([]string, 0, len(s.Departments))
+	for _, c := range s.Departments {
+		slugs = append(slugs, c.Slug)
+	}
+	return slugs
+}
+
+// resolveDepartments отделы из departments.names (или все отделы при departments.all) за вычетом
+// departments.exclude и отделов вне min_products/max_products; нечёткие совпадения и промахи пишутся в лог,
+// в строгом режиме любой промах — ошибка. NotFound и Skipped заполнены и при ошибке
+func resolveDepartments(cfg *config.Config, categories []kuper.Category, logger *slog.Logger) (departmentSelection, error) {
+	d := cfg.Departments
+
+	var sel departmentSelection
+	var selected []kuper.Category
+	if d.All {
+		selected = AllDepartments(categories)
+		logger.Info("обходим все отделы магазина", "count", len(selected))
+	} else {
+		res := ResolveCategories(d.Names, categories, ResolveOptions{MaxDistance: d.MaxDistance})
+		sel.NotFound = res.NotFound
+
+		for _, m := range res.Matches {
+			if m.Distance > 0 {
+				logger.Warn("категория найдена по похожему названию, обновите конфиг",
+					"selector", m.Selector, "name", m.Category.Name, "slug", m.Category.Slug, "distance", m.Distance)
+			}
+			selected = append(selected, m.Category)
+		}
+
+		if d.Strict {
+			if err := res.MissError(); err != nil {
+				return sel, err
+			}
+		}
+		for _, m := range res.NotFound {
+			logger.Warn("категория не найдена в магазине, пропускаю", "selector", m.Selector, "suggestions", m.Suggestions, "err", m.Err)
+		}
+	}
+
+	if len(selected) == 0 {
+		return sel, fmt.Errorf(
+			"ни одна категория из конфига не найдена для store_id=%d\n%s",
+			cfg.Kuper.StoreID,
+			BuildAvailableCategoriesHint(categories),
+		)
+	}
+
+	filtered := FilterDepartments(selected, categories, DepartmentFilter{
+		Exclude:     d.Exclude,
+		MinProducts: d.MinProducts,
+		MaxProducts: d.MaxProducts,
+	})
+	for _, m := range filtered.Unused {
+		logger.Info("исключение не совпало ни с одним отделом", "selector", m.Selector, "suggestions", m.Suggestions, "err", m.Err)
+	}
+	for _, s := range filtered.Skipped {
+		logger.Info("отдел пропущен", "name", s.Category.Name, "slug", s.Category.Slug, "reason", s.Reason)
+	}
+	sel.Departments, sel.Skipped = filtered.Kept, filtered.Skipped
+
+	if len(sel.Departments) == 0 {
+		return sel, fmt.Errorf("все %d отделов отброшены departments.exclude/min_products/max_products", len(selected))
+	}
+	return sel, nil
+}
+
+// listProductsWithPause при разомкнутом circuit breaker ждёт его закрытия (on_open=pause) или сразу прерывает работу
+func listProductsWithPause(ctx context.Context, cfg *config.Config, logger *slog.Logger, fetch func() ([]kuper.Product, error)) ([]kuper.Product, error) {
+	pauses := 0
+	for {
+		prods, err := fetch()
+
+		var openErr *client.CircuitOpenError
+		if err == nil || !errors.As(err, &openErr) {
+			return prods, err
+		}
+
+		if cfg.CircuitBreaker.OnOpen == "abort" || pauses >= cfg.CircuitBreaker.MaxPauses {
+			return nil, fmt.Errorf("circuit breaker разомкнут, работа прервана: %w", err)
+		}
+		pauses++
+
+		wait := time.Until(openErr.Until)
+		logger.Warn("circuit breaker разомкнут, пауза",
+			"key", openErr.Key, "wait", wait.Round(time.Second), "pause", pauses, "max_pauses", cfg.CircuitBreaker.MaxPauses)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
 }
 
 func ensureDir(path string) error {
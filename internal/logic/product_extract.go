@@ -2,11 +2,28 @@ package logic
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"kuperparser/internal/kuper"
+	"kuperparser/internal/money"
+	"kuperparser/storage"
 )
 
+// extractRow строка выходного файла: название, цена, ссылка и цена за кг/л/шт, если удалось определить фасовку
+func extractRow(baseURL string, p kuper.Product) storage.Row {
+	// цену, которую не удалось разобрать, не пишем; её отметит правило quality price
+	price, _ := extractPrice(p)
+	row := storage.Row{Name: extractName(p), Price: price, URL: extractURL(baseURL, p)}
+	if ps, ok := extractPackSize(p, row.Name); ok {
+		row.Pack = ps.Label
+		if up, ok := row.Price.Div(ps.Amount); ok {
+			row.UnitPrice, row.Unit = up, ps.Unit
+		}
+	}
+	return row
+}
+
 // extractName пытается достать имя товара из разных вариантов поля
 func extractName(p kuper.Product) string {
 	if v, ok := asString(p.Raw["name"]); ok {
@@ -40,39 +57,25 @@ func extractURL(baseURL string, p kuper.Product) string {
 	return ""
 }
 
-// extractPrice возвращает цену как строку, если вдруг в каком-то магазине цена будет не в целочисленном формате
-func extractPrice(p kuper.Product) string {
-	if v, ok := asString(p.Raw["price"]); ok {
-		return v
-	}
-	if v, ok := asNumberString(p.Raw["price"]); ok {
-		return v
-	}
-
+// extractPrice цена товара из первого найденного поля: price, offers[0].price, current_price, price_current;
+// значение может быть числом, строкой или объектом {amount|value, currency}.
+// Нет ни одного поля — невалидная money.Money без ошибки; поле есть, но не разбирается — ошибка
+func extractPrice(p kuper.Product) (money.Money, error) {
+	candidates := []any{p.Raw["price"]}
 	if arr, ok := p.Raw["offers"].([]any); ok && len(arr) > 0 {
 		if m, ok := arr[0].(map[string]any); ok {
-			if v, ok := asNumberString(m["price"]); ok {
-				return v
-			}
-			if pm, ok := m["price"].(map[string]any); ok {
-				if v, ok := asNumberString(pm["amount"]); ok {
-					return v
-				}
-				if v, ok := asNumberString(pm["value"]); ok {
-					return v
-				}
-			}
+			candidates = append(candidates, m["price"])
 		}
 	}
+	candidates = append(candidates, p.Raw["current_price"], p.Raw["price_current"])
 
-	if v, ok := asNumberString(p.Raw["current_price"]); ok {
-		return v
-	}
-	if v, ok := asNumberString(p.Raw["price_current"]); ok {
-		return v
+	for _, v := range candidates {
+		if v == nil || v == "" {
+			continue
+		}
+		return money.Parse(v)
 	}
-
-	return ""
+	return money.Money{}, nil
 }
 
 func asString(v any) (string, bool) {
@@ -89,7 +92,7 @@ func asNumberString(v any) (string, bool) {
 		if t == float64(int64(t)) {
 			return fmt.Sprintf("%d", int64(t)), true
 		}
-		return fmt.Sprintf("%v", t), true
+		return strconv.FormatFloat(t, 'f', -1, 64), true
 	case int:
 		return fmt.Sprintf("%d", t), true
 	case int64:
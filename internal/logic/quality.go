@@ -0,0 +1,160 @@
+package logic
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"kuperparser/internal/config"
+	"kuperparser/internal/kuper"
+	"kuperparser/storage"
+)
+
+// QualityRule правило проверки извлечённого товара (quality.rules)
+type QualityRule string
+
+const (
+	RuleRequired  QualityRule = "required"  // непустые название, цена и ссылка
+	RulePrice     QualityRule = "price"     // цена — положительное число
+	RuleURL       QualityRule = "url"       // ссылка — абсолютный http(s) адрес
+	RuleDuplicate QualityRule = "duplicate" // товар уже встречался в отделе
+	RuleOutlier   QualityRule = "outlier"   // цена изменилась с прошлого запуска больше чем в quality.outlier_ratio раз
+)
+
+// Violation нарушение правила в строке товара
+type Violation struct {
+	Rule    QualityRule `json:"rule"`
+	Slug    string      `json:"slug"`
+	Page    int         `json:"page"`
+	Name    string      `json:"name"`
+	URL     string      `json:"url"`
+	Message string      `json:"message"`
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s (slug=%s page=%d name=%q)", v.Rule, v.Message, v.Slug, v.Page, v.Name)
+}
+
+// invalidates нарушение делает строку некорректной; выброс цены только сообщается
+func (v Violation) invalidates() bool { return v.Rule != RuleOutlier }
+
+// QualityError доля строк с нарушениями выше quality.max_invalid_ratio
+type QualityError struct {
+	Invalid, Checked int
+	MaxRatio         float64
+}
+
+func (e *QualityError) Error() string {
+	return fmt.Sprintf("строк с нарушениями %d из %d (%.1f%%), допустимо не больше %.1f%% (quality.max_invalid_ratio)",
+		e.Invalid, e.Checked, 100*float64(e.Invalid)/float64(e.Checked), 100*e.MaxRatio)
+}
+
+// qualityCheck проверка строк одного отдела
+type qualityCheck struct {
+	slug         string
+	rules        []QualityRule
+	outlierRatio float64
+
+	prev map[string]float64 // цена прошлого запуска по ссылке товара
+	seen map[string]struct{}
+}
+
+// newQualityCheck для отдела slug; prev — строки файла прошлого запуска (для правила outlier)
+func newQualityCheck(cfg *config.Config, slug string, prev []storage.Row) *qualityCheck {
+	q := &qualityCheck{
+		slug:         slug,
+		outlierRatio: cfg.Quality.OutlierRatio,
+		seen:         make(map[string]struct{}),
+	}
+	for _, r := range cfg.Quality.Rules {
+		q.rules = append(q.rules, QualityRule(r))
+	}
+
+	if q.enabled(RuleOutlier) && len(prev) > 0 {
+		q.prev = make(map[string]float64, len(prev))
+		for _, r := range prev {
+			if r.Price.Valid() && r.Price.Minor() > 0 && r.URL != "" {
+				q.prev[r.URL] = r.Price.Float64()
+			}
+		}
+	}
+	return q
+}
+
+func (q *qualityCheck) enabled(r QualityRule) bool { return slices.Contains(q.rules, r) }
+
+// check нарушения в строке товара p со страницы page
+func (q *qualityCheck) check(p kuper.Product, row storage.Row, page int) []Violation {
+	var out []Violation
+	add := func(rule QualityRule, format string, args ...any) {
+		out = append(out, Violation{
+			Rule: rule, Slug: q.slug, Page: page, Name: row.Name, URL: row.URL,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	_, priceErr := extractPrice(p)
+
+	if q.enabled(RuleRequired) {
+		var empty []string
+		if strings.TrimSpace(row.Name) == "" {
+			empty = append(empty, "название")
+		}
+		// цена есть, но не разбирается — это нарушение price, а не required
+		if !row.Price.Valid() && priceErr == nil {
+			empty = append(empty, "цена")
+		}
+		if strings.TrimSpace(row.URL) == "" {
+			empty = append(empty, "ссылка")
+		}
+		if len(empty) > 0 {
+			add(RuleRequired, "пусто: %s", strings.Join(empty, ", "))
+		}
+	}
+
+	if q.enabled(RulePrice) {
+		switch {
+		case priceErr != nil:
+			add(RulePrice, "%v", priceErr)
+		case row.Price.Valid() && row.Price.Minor() <= 0:
+			add(RulePrice, "цена %s не больше нуля", row.Price)
+		}
+	}
+
+	if q.enabled(RuleURL) && row.URL != "" {
+		if u, err := url.Parse(row.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add(RuleURL, "некорректная ссылка %q", row.URL)
+		}
+	}
+
+	if q.enabled(RuleDuplicate) {
+		if key := productKey(p, row); key != "" {
+			if _, dup := q.seen[key]; dup {
+				add(RuleDuplicate, "товар %s уже записан", key)
+			}
+			q.seen[key] = struct{}{}
+		}
+	}
+
+	if q.enabled(RuleOutlier) && row.Price.Valid() && row.Price.Minor() > 0 {
+		if old, ok := q.prev[row.URL]; ok {
+			price := row.Price.Float64()
+			if ratio := max(price/old, old/price); ratio > q.outlierRatio {
+				add(RuleOutlier, "цена %s, в прошлый раз %.2f (в %.1f раза)", row.Price, old, ratio)
+			}
+		}
+	}
+
+	return out
+}
+
+// productKey id товара из ответа API, иначе ссылка
+func productKey(p kuper.Product, row storage.Row) string {
+	for _, k := range []string{"id", "sku", "product_id"} {
+		if v, ok := asNumberString(p.Raw[k]); ok {
+			return k + "=" + v
+		}
+	}
+	return row.URL
+}
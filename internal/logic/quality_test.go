@@ -0,0 +1,247 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"kuperparser/internal/client"
+	"kuperparser/internal/config"
+	"kuperparser/internal/kuper"
+	"kuperparser/internal/money"
+	"kuperparser/storage"
+)
+
+func product(raw map[string]any) kuper.Product { return kuper.Product{Raw: raw} }
+
+func violatedRules(vs []Violation) []QualityRule {
+	var out []QualityRule
+	for _, v := range vs {
+		out = append(out, v.Rule)
+	}
+	return out
+}
+
+func TestQualityCheckRules(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  map[string]any
+		want []QualityRule
+	}{
+		{"корректный товар", map[string]any{"id": 1.0, "name": "Молоко 930 мл", "price": 109.99, "permalink": "/products/1"}, nil},
+		{"нет названия", map[string]any{"id": 2.0, "price": 10.0, "permalink": "/products/2"}, []QualityRule{RuleRequired}},
+		{"нет цены", map[string]any{"id": 3.0, "name": "Хлеб", "permalink": "/products/3"}, []QualityRule{RuleRequired}},
+		{"нет ссылки", map[string]any{"id": 4.0, "name": "Хлеб", "price": 45.0}, []QualityRule{RuleRequired}},
+		// цена есть, но не разбирается — нарушение price, а не required
+		{"цена не число", map[string]any{"id": 5.0, "name": "Сыр", "price": "по запросу", "permalink": "/products/5"}, []QualityRule{RulePrice}},
+		{"нулевая цена", map[string]any{"id": 6.0, "name": "Сыр", "price": 0.0, "permalink": "/products/6"}, []QualityRule{RulePrice}},
+		{"отрицательная цена", map[string]any{"id": 7.0, "name": "Сыр", "price": "-5,00", "permalink": "/products/7"}, []QualityRule{RulePrice}},
+		// ссылки не http(s) из ответа не берутся — ссылки нет
+		{"ссылка ftp", map[string]any{"id": 8.0, "name": "Сыр", "price": 5.0, "url": "ftp://kuper.ru/products/8"}, []QualityRule{RuleRequired}},
+		{"ссылка без хоста", map[string]any{"id": 9.0, "name": "Сыр", "price": 5.0, "canonical_url": "http://"}, []QualityRule{RuleURL}},
+	}
+	cfg := config.Default()
+	for _, tt := range tests {
+		qc := newQualityCheck(cfg, "moloko", nil)
+		p := product(tt.raw)
+		got := violatedRules(qc.check(p, extractRow("https://kuper.ru", p), 1))
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: нарушения %v, ожидалось %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestQualityCheckURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want []QualityRule
+	}{
+		{"https://kuper.ru/products/1", nil},
+		{"http://localhost:8081/products/1", nil},
+		{"ftp://kuper.ru/products/1", []QualityRule{RuleURL}},
+		{"https:///products/1", []QualityRule{RuleURL}},
+		{"products/1", []QualityRule{RuleURL}},
+	}
+	cfg := config.Default()
+	for _, tt := range tests {
+		qc := newQualityCheck(cfg, "moloko", nil)
+		row := storage.Row{Name: "Молоко", Price: money.New(10999, ""), URL: tt.url}
+		got := violatedRules(qc.check(product(map[string]any{"name": "Молоко"}), row, 1))
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%q: нарушения %v, ожидалось %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestQualityCheckDuplicate(t *testing.T) {
+	qc := newQualityCheck(config.Default(), "moloko", nil)
+	items := []struct {
+		raw  map[string]any
+		want []QualityRule
+	}{
+		{map[string]any{"id": 1.0, "name": "Молоко", "price": 100.0, "permalink": "/products/1"}, nil},
+		// тот же id с другой ссылкой — дубль
+		{map[string]any{"id": 1.0, "name": "Молоко", "price": 100.0, "permalink": "/products/1-new"}, []QualityRule{RuleDuplicate}},
+		// без id товар определяется по ссылке
+		{map[string]any{"name": "Кефир", "price": 90.0, "permalink": "/products/2"}, nil},
+		{map[string]any{"name": "Кефир", "price": 90.0, "permalink": "/products/2"}, []QualityRule{RuleDuplicate}},
+		{map[string]any{"id": 3.0, "name": "Сыр", "price": 300.0, "permalink": "/products/3"}, nil},
+	}
+	for i, it := range items {
+		p := product(it.raw)
+		got := violatedRules(qc.check(p, extractRow("https://kuper.ru", p), 1))
+		if !slices.Equal(got, it.want) {
+			t.Errorf("товар %d: нарушения %v, ожидалось %v", i, got, it.want)
+		}
+	}
+
+	// в другом отделе тот же товар — не дубль
+	other := newQualityCheck(config.Default(), "kefir", nil)
+	p := product(items[0].raw)
+	if got := other.check(p, extractRow("https://kuper.ru", p), 1); len(got) != 0 {
+		t.Errorf("в другом отделе: %v", got)
+	}
+}
+
+func TestQualityCheckOutlier(t *testing.T) {
+	prev := []storage.Row{
+		{URL: "https://kuper.ru/products/1", Price: money.New(10000, "")},
+		{URL: "https://kuper.ru/products/2", Price: money.New(10000, "")},
+		{URL: "https://kuper.ru/products/3", Price: money.New(10000, "")},
+		{URL: "https://kuper.ru/products/4", Price: money.Money{}}, // без цены не сравнивается
+	}
+	tests := []struct {
+		url   string
+		price float64
+		want  []QualityRule
+	}{
+		{"/products/1", 499.0, nil},                           // в 4.99 раза — в пределах outlier_ratio
+		{"/products/2", 100000.0, []QualityRule{RuleOutlier}}, // цена в 1000 раз выше
+		{"/products/3", 1.0, []QualityRule{RuleOutlier}},      // и в 100 раз ниже
+		{"/products/4", 1.0, nil},
+		{"/products/5", 1.0, nil}, // нового товара не было в прошлом запуске
+	}
+	cfg := config.Default()
+	qc := newQualityCheck(cfg, "moloko", prev)
+	for _, tt := range tests {
+		p := product(map[string]any{"name": "Товар", "price": tt.price, "permalink": tt.url})
+		vs := qc.check(p, extractRow("https://kuper.ru", p), 1)
+		if got := violatedRules(vs); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: нарушения %v, ожидалось %v", tt.url, got, tt.want)
+		}
+		// выброс цены только сообщается
+		for _, v := range vs {
+			if v.invalidates() {
+				t.Errorf("%s: %v делает строку некорректной", tt.url, v)
+			}
+		}
+	}
+
+	// правило выключено — прошлый запуск не читается
+	cfg.Quality.Rules = []string{"required"}
+	if qc := newQualityCheck(cfg, "moloko", prev); qc.prev != nil {
+		t.Error("outlier выключен, но цены прошлого запуска загружены")
+	}
+}
+
+func TestQualityCheckEnabledRules(t *testing.T) {
+	cfg := config.Default()
+	cfg.Quality.Rules = []string{"url"}
+	qc := newQualityCheck(cfg, "moloko", nil)
+
+	// пустое название и цена не проверяются, только ссылка
+	row := storage.Row{URL: "kuper.ru/products/1"}
+	got := violatedRules(qc.check(product(map[string]any{}), row, 1))
+	if !slices.Equal(got, []QualityRule{RuleURL}) {
+		t.Errorf("нарушения %v, ожидалось только url", got)
+	}
+}
+
+// qualityCassette магазин с одним отделом: две корректные строки, без цены и с отрицательной ценой
+func qualityCassette(t *testing.T) string {
+	t.Helper()
+	const base = "https://kuper.ru/api/v3/stores/960"
+	page := `{"products": [
+		{"id": 1, "name": "Молоко 930 мл", "price": 109.99, "permalink": "/products/1"},
+		{"id": 2, "name": "Кефир 930 г", "price": 89.9, "permalink": "/products/2"},
+		{"id": 3, "name": "Сыр 200 г", "permalink": "/products/3"},
+		{"id": 4, "name": "Йогурт 120 г", "price": -5, "permalink": "/products/4"}
+	]}`
+	json200 := func(u, body string) client.Interaction {
+		return client.Interaction{
+			Request:  client.RecordedRequest{Method: "GET", URL: u},
+			Response: client.RecordedResponse{Status: 200, Body: body},
+		}
+	}
+	cas := map[string]any{"interactions": []client.Interaction{
+		json200(base+"/categories", `{"categories": [{"id": 101, "type": "Department", "name": "Молоко", "slug": "moloko", "products_count": 4}]}`),
+		json200("https://kuper.ru/api/stores/960", `{"store": {"id": 960, "name": "Магнит", "location": {"full_address": "Одинцово"}, "retailer": {"name": "Магнит"}}}`),
+		json200(base+"/departments/moloko?offers_limit=10&page=1&per_page=5", page),
+		json200(base+"/departments/moloko?offers_limit=10&page=2&per_page=5", `{"products": []}`),
+	}}
+	b, err := json.Marshal(cas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "quality.json")
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunQuality(t *testing.T) {
+	tests := []struct {
+		name     string
+		drop     bool
+		maxRatio float64
+		rows     int
+		dropped  int
+		failed   bool
+	}{
+		{"строки с нарушениями записываются", false, 0, 4, 0, false},
+		{"drop_invalid", true, 0, 2, 2, false},
+		{"доля нарушений в пределах", false, 0.5, 4, 0, false},
+		{"доля нарушений выше max_invalid_ratio", false, 0.25, 4, 0, true},
+		// отброшенные строки тоже считаются в доле нарушений
+		{"drop_invalid выше max_invalid_ratio", true, 0.25, 2, 2, true},
+	}
+	for _, tt := range tests {
+		cfg := replayConfig(t)
+		cfg.HTTP.Cassette.Path = qualityCassette(t)
+		cfg.Departments.Names = []string{"slug:moloko"}
+		cfg.Quality.DropInvalid = tt.drop
+		cfg.Quality.MaxInvalidRatio = tt.maxRatio
+
+		summary, err := Run(quietContext(), cfg)
+		var qe *QualityError
+		if tt.failed != errors.As(err, &qe) {
+			t.Errorf("%s: ошибка %v, ожидалась QualityError: %v", tt.name, err, tt.failed)
+			continue
+		}
+		if tt.failed && (qe.Invalid != 2 || qe.Checked != 4 || summary.Status != StatusFailed) {
+			t.Errorf("%s: %+v, статус %s", tt.name, qe, summary.Status)
+		}
+		if !tt.failed && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+
+		q := summary.Quality
+		if q.Checked != 4 || q.Invalid != 2 || q.Dropped != tt.dropped || summary.Rows != tt.rows {
+			t.Errorf("%s: проверено %d, нарушений %d, отброшено %d, записано %d; ожидалось 4, 2, %d, %d",
+				tt.name, q.Checked, q.Invalid, q.Dropped, summary.Rows, tt.dropped, tt.rows)
+		}
+		if q.ByRule[RuleRequired] != 1 || q.ByRule[RulePrice] != 1 || len(q.Violations) != 2 {
+			t.Errorf("%s: по правилам %v, нарушения %v", tt.name, q.ByRule, q.Violations)
+		}
+
+		// файл пишется и при неудачной проверке качества
+		rows, err := storage.ReadFile(cfg.Output.Format, summary.Departments[0].File)
+		if err != nil || len(rows) != tt.rows {
+			t.Errorf("%s: в файле %d строк (%v), ожидалось %d", tt.name, len(rows), err, tt.rows)
+		}
+	}
+}
@@ -2,54 +2,367 @@ package logic
 
 import (
 	"fmt"
+	"path"
 	"regexp"
+	"sort"
 	"strings"
 
+	"kuperparser/internal/config"
 	"kuperparser/internal/kuper"
 )
 
+// ResolveOptions настройки сопоставления отделов
+type ResolveOptions struct {
+	// MaxDistance наибольшее расстояние Левенштейна, при котором название считается совпавшим нечётко; 0 — только точное.
+	// Для коротких названий предел ниже: не больше четверти длины, короче minFuzzyRunes — только точное
+	MaxDistance int
+}
+
+// CategoryMatch отдел магазина, выбранный элементом конфига
+type CategoryMatch struct {
+	Selector string
+	Category kuper.Category
+	Distance int // > 0 — совпадение нечёткое
+}
+
+// CategoryMiss элемент конфига, для которого отдел не найден
+type CategoryMiss struct {
+	Selector    string
+	Suggestions []string // ближайшие по названию отделы
+	Err         error    // некорректный селектор
+}
+
+func (m CategoryMiss) String() string {
+	if m.Err != nil {
+		return m.Err.Error()
+	}
+	if len(m.Suggestions) == 0 {
+		return fmt.Sprintf("%q не найдена", m.Selector)
+	}
+	return fmt.Sprintf("%q не найдена, возможно: %s", m.Selector, strings.Join(m.Suggestions, "; "))
+}
+
 // ResolveResult результат сопоставления категорий из конфига с категориями магазина
 type ResolveResult struct {
-	Slugs []string // список уникальных названий категорий для магазина сопоставленных с указанными в конфиге
+	Slugs []string // список уникальных slug'ов в порядке конфига
 
-	NotFoundNames []string
+	Matches  []CategoryMatch
+	NotFound []CategoryMiss
 }
 
-// ResolveCategorySlugsByNames сопоставляет список имён категорий из конфига со списком категорий магазина
-func ResolveCategorySlugsByNames(requestedNames []string, storeCategories []kuper.Category) ResolveResult {
-	index := make(map[string]kuper.Category, len(storeCategories))
+// MissError ошибка строгого режима со всеми ненайденными отделами
+func (r ResolveResult) MissError() error {
+	if len(r.NotFound) == 0 {
+		return nil
+	}
+	lines := make([]string, 0, len(r.NotFound))
+	for _, m := range r.NotFound {
+		lines = append(lines, "  - "+m.String())
+	}
+	return fmt.Errorf("не найдены отделы (departments.strict):\n%s", strings.Join(lines, "\n"))
+}
+
+// ResolveCategories сопоставляет элементы departments.names (названия и селекторы slug:/id:/glob:/re:,
+// см. config.ParseSelector) с категориями магазина
+func ResolveCategories(selectors []string, storeCategories []kuper.Category, opts ResolveOptions) ResolveResult {
+	byName := make(map[string]kuper.Category, len(storeCategories))
 	for _, c := range storeCategories {
 		n := normalizeCategoryName(c.Name)
-		if _, ok := index[n]; !ok {
-			index[n] = c
+		if _, ok := byName[n]; !ok {
+			byName[n] = c
 		}
 	}
 
 	var res ResolveResult
-	res.Slugs = make([]string, 0, len(requestedNames))
-
+	res.Slugs = make([]string, 0, len(selectors))
 	seenSlug := make(map[string]struct{})
 
-	for _, rawName := range requestedNames {
-		n := normalizeCategoryName(rawName)
-		cat, ok := index[n]
-		if !ok {
-			res.NotFoundNames = append(res.NotFoundNames, rawName)
+	add := func(sel string, c kuper.Category, dist int) {
+		// дубль проверка категорий конфига
+		if _, exists := seenSlug[c.Slug]; exists {
+			return
+		}
+		seenSlug[c.Slug] = struct{}{}
+		res.Slugs = append(res.Slugs, c.Slug)
+		res.Matches = append(res.Matches, CategoryMatch{Selector: sel, Category: c, Distance: dist})
+	}
+
+	for _, raw := range selectors {
+		sel, err := config.ParseSelector(raw)
+		if err != nil {
+			res.NotFound = append(res.NotFound, CategoryMiss{Selector: raw, Err: err})
 			continue
 		}
 
-		// дубль проверка категорий конфига
-		if _, exists := seenSlug[cat.Slug]; exists {
+		var found []kuper.Category
+		dist := 0
+
+		switch sel.Kind {
+		case config.SelectName:
+			if c, ok := byName[normalizeCategoryName(sel.Value)]; ok {
+				found = append(found, c)
+				break
+			}
+			// название совпало со slug'ом
+			if c, ok := findBySlug(storeCategories, strings.ToLower(sel.Value)); ok {
+				found = append(found, c)
+				break
+			}
+			if c, d, ok := closestByName(storeCategories, sel.Value, opts.MaxDistance); ok {
+				found, dist = append(found, c), d
+			}
+		case config.SelectSlug:
+			if c, ok := findBySlug(storeCategories, sel.Value); ok {
+				found = append(found, c)
+			}
+		case config.SelectID:
+			for _, c := range storeCategories {
+				if c.ID == sel.ID {
+					found = append(found, c)
+					break
+				}
+			}
+		case config.SelectGlob:
+			for _, c := range storeCategories {
+				if ok, _ := path.Match(sel.Value, normalizeCategoryName(c.Name)); ok {
+					found = append(found, c)
+				}
+			}
+		case config.SelectRe:
+			for _, c := range storeCategories {
+				if sel.Re.MatchString(c.Name) {
+					found = append(found, c)
+				}
+			}
+		}
+
+		if len(found) == 0 {
+			res.NotFound = append(res.NotFound, CategoryMiss{Selector: raw, Suggestions: suggestCategories(storeCategories, sel.Value, 3)})
 			continue
 		}
-		seenSlug[cat.Slug] = struct{}{}
+		for _, c := range found {
+			add(raw, c, dist)
+		}
+	}
+
+	return res
+}
+
+// DepartmentFilter исключения и ограничения по числу товаров для выбранных отделов
+type DepartmentFilter struct {
+	Exclude     []string // названия и селекторы, как в departments.names, но без нечёткого совпадения
+	MinProducts int
+	MaxProducts int // 0 — без ограничения
+}
+
+// SkippedDepartment отдел, отброшенный фильтром
+type SkippedDepartment struct {
+	Category kuper.Category
+	Reason   string
+}
 
-		res.Slugs = append(res.Slugs, cat.Slug)
+// FilterResult результат FilterDepartments
+type FilterResult struct {
+	Kept    []kuper.Category
+	Skipped []SkippedDepartment
+	// Unused исключения, не совпавшие ни с одним отделом магазина (скорее всего опечатка)
+	Unused []CategoryMiss
+}
+
+// AllDepartments все отделы магазина (type=department) в порядке ответа API
+func AllDepartments(categories []kuper.Category) []kuper.Category {
+	var deps []kuper.Category
+	for _, c := range categories {
+		if strings.EqualFold(c.Type, "department") {
+			deps = append(deps, c)
+		}
+	}
+	return deps
+}
+
+// FilterDepartments убирает из selected исключённые отделы и отделы с числом товаров вне [MinProducts, MaxProducts];
+// исключения ищутся среди всех категорий магазина storeCategories
+func FilterDepartments(selected, storeCategories []kuper.Category, f DepartmentFilter) FilterResult {
+	var res FilterResult
+
+	excluded := make(map[string]string)
+	if len(f.Exclude) > 0 {
+		ex := ResolveCategories(f.Exclude, storeCategories, ResolveOptions{})
+		for _, m := range ex.Matches {
+			excluded[m.Category.Slug] = m.Selector
+		}
+		res.Unused = ex.NotFound
 	}
 
+	for _, c := range selected {
+		reason := ""
+		switch sel, ok := excluded[c.Slug]; {
+		case ok:
+			reason = fmt.Sprintf("исключён (%s)", sel)
+		case c.ProductsCount < f.MinProducts:
+			reason = fmt.Sprintf("товаров %d < min_products %d", c.ProductsCount, f.MinProducts)
+		case f.MaxProducts > 0 && c.ProductsCount > f.MaxProducts:
+			reason = fmt.Sprintf("товаров %d > max_products %d", c.ProductsCount, f.MaxProducts)
+		}
+		if reason != "" {
+			res.Skipped = append(res.Skipped, SkippedDepartment{Category: c, Reason: reason})
+			continue
+		}
+		res.Kept = append(res.Kept, c)
+	}
 	return res
 }
 
+// slugHashRe хэш-суффикс, который Kuper добавляет к slug'у и меняет при переименовании отдела
+var slugHashRe = regexp.MustCompile(`-[0-9a-f]{6,}$`)
+
+func slugStem(s string) string { return slugHashRe.ReplaceAllString(s, "") }
+
+// findBySlug точное совпадение slug'а, иначе — совпадение без хэш-суффикса
+func findBySlug(categories []kuper.Category, slug string) (kuper.Category, bool) {
+	for _, c := range categories {
+		if c.Slug == slug {
+			return c, true
+		}
+	}
+	stem := slugStem(slug)
+	for _, c := range categories {
+		if slugStem(c.Slug) == stem {
+			return c, true
+		}
+	}
+	return kuper.Category{}, false
+}
+
+// minFuzzyRunes короче этого название нечётко не сопоставляется: «Чай» в трёх правках от «Сок»
+const minFuzzyRunes = 6
+
+// fuzzyLimit допустимое число правок для названия name: не больше четверти его длины и не больше maxDist
+func fuzzyLimit(name string, maxDist int) int {
+	n := len([]rune(name))
+	if n < minFuzzyRunes {
+		return 0
+	}
+	return min(maxDist, n/4)
+}
+
+// closestByName единственный ближайший по названию отдел в пределах fuzzyLimit(name, maxDist)
+func closestByName(categories []kuper.Category, name string, maxDist int) (kuper.Category, int, bool) {
+	n := normalizeCategoryName(name)
+	maxDist = fuzzyLimit(n, maxDist)
+	if maxDist <= 0 {
+		return kuper.Category{}, 0, false
+	}
+
+	best, bestDist, ties := kuper.Category{}, maxDist+1, 0
+	for _, c := range departmentsOrAll(categories) {
+		d := levenshtein(n, normalizeCategoryName(c.Name))
+		switch {
+		case d < bestDist:
+			best, bestDist, ties = c, d, 1
+		case d == bestDist && c.Slug != best.Slug:
+			ties++
+		}
+	}
+	// два одинаково близких отдела — выбирать наугад нельзя
+	if bestDist > maxDist || ties != 1 {
+		return kuper.Category{}, 0, false
+	}
+	return best, bestDist, true
+}
+
+// suggestCategories до n ближайших по названию отделов для подсказки «возможно, имелось в виду»
+func suggestCategories(categories []kuper.Category, name string, n int) []string {
+	target := normalizeCategoryName(name)
+
+	type cand struct {
+		name string
+		dist int
+	}
+	var cands []cand
+	for _, c := range departmentsOrAll(categories) {
+		norm := normalizeCategoryName(c.Name)
+		d := levenshtein(target, norm)
+		// вхождение одного в другое считаем близким независимо от длины
+		if target != "" && (strings.Contains(norm, target) || strings.Contains(target, norm)) {
+			d = min(d, 1)
+		} else if sharesWordStem(target, norm) {
+			d = min(d, 2)
+		}
+		// совсем непохожие не предлагаем
+		if d > max(len([]rune(target)), len([]rune(norm)))/2 {
+			continue
+		}
+		cands = append(cands, cand{c.Name, d})
+	}
+	sort.SliceStable(cands, func(i, j int) bool { return cands[i].dist < cands[j].dist })
+
+	out := make([]string, 0, n)
+	for _, c := range cands {
+		if len(out) == n {
+			break
+		}
+		out = append(out, c.name)
+	}
+	return out
+}
+
+var wordRe = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// sharesWordStem есть слова с общим началом хотя бы из 4 букв («молочка» и «молоко»)
+func sharesWordStem(a, b string) bool {
+	const stem = 4
+	for _, wa := range wordRe.FindAllString(a, -1) {
+		ra := []rune(wa)
+		if len(ra) < stem {
+			continue
+		}
+		for _, wb := range wordRe.FindAllString(b, -1) {
+			rb := []rune(wb)
+			if len(rb) >= stem && string(ra[:stem]) == string(rb[:stem]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// departmentsOrAll категории type=department, если они есть в ответе, иначе все
+func departmentsOrAll(categories []kuper.Category) []kuper.Category {
+	var deps []kuper.Category
+	for _, c := range categories {
+		if strings.EqualFold(c.Type, "department") {
+			deps = append(deps, c)
+		}
+	}
+	if len(deps) == 0 {
+		return categories
+	}
+	return deps
+}
+
+// levenshtein расстояние редактирования по рунам
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
 // BuildAvailableCategoriesHint возвращает строку со списком доступных категорий
 func BuildAvailableCategoriesHint(storeCategories []kuper.Category) string {
 	lines := make([]string, 0, len(storeCategories))
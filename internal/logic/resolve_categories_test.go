@@ -0,0 +1,87 @@
+package logic
+
+import (
+	"testing"
+
+	"kuperparser/internal/kuper"
+)
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "сыр", 3},
+		{"молоко", "молоко", 0},
+		{"молоко", "малако", 2},
+		{"хлеб", "хлеба", 1},
+		{"чай", "сок", 3},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, ожидалось %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := levenshtein(tt.b, tt.a); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, ожидалось %d", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestFuzzyLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		maxDist int
+		want    int
+	}{
+		{"чай", 3, 0},
+		{"сыры", 3, 0},
+		{"напиток", 3, 1},
+		{"хлеб, выпечка", 3, 3},
+		{"молоко, сыр, яйца", 3, 3},
+		{"молоко, сыр, яйца", 1, 1},
+		{"молоко, сыр, яйца", 0, 0},
+	}
+	for _, tt := range tests {
+		if got := fuzzyLimit(tt.name, tt.maxDist); got != tt.want {
+			t.Errorf("fuzzyLimit(%q, %d) = %d, ожидалось %d", tt.name, tt.maxDist, got, tt.want)
+		}
+	}
+}
+
+func TestClosestByName(t *testing.T) {
+	categories := []kuper.Category{
+		{ID: 1, Type: "department", Name: "Молоко, сыр, яйца", Slug: "moloko-syr-yaytsa"},
+		{ID: 2, Type: "department", Name: "Хлеб, выпечка", Slug: "khleb-vypechka"},
+		{ID: 3, Type: "department", Name: "Сок", Slug: "sok"},
+		{ID: 4, Type: "department", Name: "Напитки", Slug: "napitki"},
+		{ID: 5, Type: "department", Name: "Напитки2", Slug: "napitki-2"},
+	}
+	tests := []struct {
+		name     string
+		wantSlug string // пусто — совпадения быть не должно
+		wantDist int
+	}{
+		{"Молоко, сыр, яица", "moloko-syr-yaytsa", 1},
+		{"  хлеб,  выпечко ", "khleb-vypechka", 1},
+		// короткие названия — только точно: «Чай» в трёх правках от «Сок»
+		{"Чай", "", 0},
+		{"Сокк", "", 0},
+		// два одинаково близких отдела
+		{"Напитки1", "", 0},
+		{"Мясо, птица", "", 0},
+	}
+	for _, tt := range tests {
+		c, dist, ok := closestByName(categories, tt.name, 3)
+		if tt.wantSlug == "" {
+			if ok {
+				t.Errorf("closestByName(%q) = %s, совпадения быть не должно", tt.name, c.Slug)
+			}
+			continue
+		}
+		if !ok || c.Slug != tt.wantSlug || dist != tt.wantDist {
+			t.Errorf("closestByName(%q) = %s, %d, %v, ожидалось %s, %d", tt.name, c.Slug, dist, ok, tt.wantSlug, tt.wantDist)
+		}
+	}
+}
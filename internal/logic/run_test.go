@@ -0,0 +1,117 @@
+package logic
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"kuperparser/internal/config"
+	"kuperparser/internal/logging"
+)
+
+// replayConfig конфиг магазина 960 с ответами из testdata/kupermock.json — запуска с departments.all
+// против cmd/kupermock в режиме http.cassette.mode: record
+func replayConfig(t *testing.T) *config.Config {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Kuper.StoreID = 960
+	cfg.Output.Directory = t.TempDir()
+	cfg.HTTP.Cassette.Mode = "replay"
+	cfg.HTTP.Cassette.Path = "testdata/kupermock.json"
+	return cfg
+}
+
+func quietContext() context.Context {
+	return logging.NewContext(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestRunReplay(t *testing.T) {
+	cfg := replayConfig(t)
+	cfg.Departments.All = true
+
+	summary, err := Run(quietContext(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Status != StatusOK {
+		t.Errorf("статус %q, ожидался %q", summary.Status, StatusOK)
+	}
+	if summary.Rows != 21 {
+		t.Errorf("строк %d, ожидалось 21", summary.Rows)
+	}
+
+	wantRows := map[string]int{
+		"moloko-syr-yaytsa":     7,
+		"ovoshchi-frukty-zelen": 6,
+		"khleb-vypechka":        3,
+		"napitki":               3,
+		"myaso-ptitsa":          2,
+	}
+	if len(summary.Departments) != len(wantRows) {
+		t.Fatalf("отделов %d, ожидалось %d", len(summary.Departments), len(wantRows))
+	}
+	var moloko DepartmentSummary
+	for _, d := range summary.Departments {
+		if d.Rows != wantRows[d.Slug] || d.Rows != d.Expected {
+			t.Errorf("%s: строк %d, products_count %d, ожидалось %d", d.Slug, d.Rows, d.Expected, wantRows[d.Slug])
+		}
+		if d.Slug == "moloko-syr-yaytsa" {
+			moloko = d
+		}
+	}
+	if _, err := os.Stat(SummaryPath(cfg.Output.Directory, 960)); err != nil {
+		t.Errorf("summary не записан: %v", err)
+	}
+
+	f, err := os.Open(moloko.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.Comma = ';'
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 8 {
+		t.Fatalf("в CSV %d строк, ожидалось 8 с заголовком", len(records))
+	}
+
+	header := records[0]
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	col := make(map[string]int, len(header))
+	for i, h := range header {
+		col[h] = i
+	}
+	first := records[1]
+	want := map[string]string{
+		"Имя товара":      "Молоко Простоквашино пастеризованное 3,2%, 930 мл",
+		"Цена":            "109,99",
+		"Ссылка":          "https://kuper.ru/products/500101-moloko-prostokvashino-3-2-930-ml",
+		"Фасовка":         "930 мл",
+		"Цена за единицу": "118,27",
+		"Единица":         "л",
+		"Сеть":            "Магнит",
+		"Подотдел":        "Молоко",
+		"Страница":        "1",
+		"Позиция":         "1",
+	}
+	for name, v := range want {
+		i, ok := col[name]
+		if !ok {
+			t.Errorf("нет колонки %q", name)
+			continue
+		}
+		if first[i] != v {
+			t.Errorf("%s = %q, ожидалось %q", name, first[i], v)
+		}
+	}
+	if got := records[4][col["Подотдел"]]; got != "Сыр" {
+		t.Errorf("подотдел четвёртого товара %q, ожидался «Сыр»", got)
+	}
+}
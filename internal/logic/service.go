@@ -0,0 +1,172 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"kuperparser/internal/client"
+	"kuperparser/internal/config"
+	"kuperparser/internal/kuper"
+	"kuperparser/internal/logging"
+)
+
+// BaseURL адрес сайта из конфига без завершающего слэша
+func BaseURL(cfg *config.Config) string {
+	return strings.TrimRight(cfg.Kuper.BaseURL, "/")
+}
+
+// NewService собирает клиент kuper со всем транспортом из конфига (прокси, ретраи, лимиты, кэш, сессии).
+// Логгер берётся из ctx, метрики транспорта пишутся в m (nil — не собираются).
+// Возвращаемая функция сохраняет cookie сессий и вызывается после работы с клиентом
+func NewService(ctx context.Context, cfg *config.Config, m *client.Metrics) (kuper.KuperService, func(), error) {
+	logger := logging.FromContext(ctx, nil)
+
+	// конфиг уже проверен (config.Validate): ошибки разбора ниже — только на случай конфига, собранного вручную
+	timeout := time.Duration(cfg.HTTP.TimeoutSeconds) * time.Second
+
+	httpClient := client.NewHTTPClient(timeout, logger)
+
+	retryErrors, err := client.ParseErrorClasses(cfg.HTTP.Retry.Errors)
+	if err != nil {
+		return nil, nil, fmt.Errorf("http.retry.errors: %w", err)
+	}
+
+	tcfg := client.TransportConfig{
+		Timeout: timeout,
+		Retry: client.RetryPolicy{
+			MaxRetries:    cfg.HTTP.Retries,
+			Statuses:      cfg.HTTP.Retry.Statuses,
+			Errors:        retryErrors,
+			BaseDelay:     time.Duration(cfg.HTTP.Retry.BaseDelayMS) * time.Millisecond,
+			MaxDelay:      time.Duration(cfg.HTTP.Retry.MaxDelayMS) * time.Millisecond,
+			Jitter:        cfg.HTTP.Retry.Jitter,
+			MaxElapsed:    time.Duration(cfg.HTTP.Retry.MaxElapsedSeconds) * time.Second,
+			MaxRetryAfter: time.Duration(cfg.HTTP.Retry.MaxRetryAfterSeconds) * time.Second,
+			BudgetRatio:   cfg.HTTP.Retry.BudgetRatio,
+		},
+		Workers: cfg.Concurrency.Workers,
+		Order:   cfg.HTTP.Middleware,
+		Metrics: m,
+		Logger:  logger,
+		Cassette: client.CassetteConfig{
+			Mode: client.CassetteMode(cfg.HTTP.Cassette.Mode),
+			Path: cfg.HTTP.Cassette.Path,
+		},
+		RateLimit: client.RateLimitConfig{
+			RPS:         cfg.RateLimit.RPS,
+			Burst:       cfg.RateLimit.Burst,
+			MinRPS:      cfg.RateLimit.MinRPS,
+			RecoverStep: cfg.RateLimit.RecoverStep,
+		},
+		ProxyRateLimit: client.RateLimitConfig{
+			RPS:         cfg.RateLimit.PerProxyRPS,
+			Burst:       cfg.RateLimit.PerProxyBurst,
+			MinRPS:      cfg.RateLimit.MinRPS,
+			RecoverStep: cfg.RateLimit.RecoverStep,
+		},
+	}
+
+	if cfg.Cache.Enabled {
+		tcfg.Cache = client.CacheConfig{
+			Dir:      cfg.Cache.Directory,
+			TTL:      time.Duration(cfg.Cache.TTLSeconds) * time.Second,
+			MaxBytes: int64(cfg.Cache.MaxMB) << 20,
+		}
+	}
+
+	if tcfg.HeaderProfiles, err = headerProfiles(cfg); err != nil {
+		return nil, nil, err
+	}
+
+	// Сессии: cookie на диске и прогрев перед запросами к API; {store_id} в адресах и cookie — kuper.store_id
+	baseURL := BaseURL(cfg)
+	withStore := strings.NewReplacer("{store_id}", strconv.Itoa(cfg.Kuper.StoreID))
+
+	cookies := client.NewCookieStore(cfg.Session.CookiesDir)
+	if u, err := url.Parse(baseURL); err == nil {
+		seed := make(map[string]string, len(cfg.Session.Cookies))
+		for name, v := range cfg.Session.Cookies {
+			seed[name] = withStore.Replace(v)
+		}
+		cookies.Seed(u, seed)
+	}
+	tcfg.Cookies = cookies
+
+	if cfg.Session.Warmup {
+		urls := cfg.Session.WarmupURLs
+		if len(urls) == 0 {
+			// главная, затем HTML-страница магазина: cookie выбранного магазина сайт выставляет при её открытии
+			urls = []string{baseURL + "/", baseURL + "/stores/{store_id}"}
+		}
+		for _, u := range urls {
+			tcfg.Warmup.URLs = append(tcfg.Warmup.URLs, withStore.Replace(u))
+		}
+	}
+
+	blockPolicy, err := client.ParseBlockPolicy(cfg.AntiBot.Policy)
+	if err != nil {
+		return nil, nil, fmt.Errorf("antibot.policy: %w", err)
+	}
+	tcfg.Block = client.BlockConfig{
+		Policy:      blockPolicy,
+		CooldownFor: time.Duration(cfg.AntiBot.CooldownSeconds) * time.Second,
+	}
+
+	openTimeout := time.Duration(cfg.CircuitBreaker.OpenSeconds) * time.Second
+	tcfg.Breaker = client.BreakerConfig{
+		FailureThreshold: cfg.CircuitBreaker.FailureThreshold,
+		OpenTimeout:      openTimeout,
+		HalfOpenRequests: cfg.CircuitBreaker.HalfOpenRequests,
+	}
+	tcfg.ProxyBreaker = client.BreakerConfig{
+		FailureThreshold: cfg.CircuitBreaker.PerProxyFailureThreshold,
+		OpenTimeout:      openTimeout,
+		HalfOpenRequests: cfg.CircuitBreaker.HalfOpenRequests,
+	}
+
+	tcfg.Chaos = cfg.ChaosConfig()
+	if tcfg.Chaos.Enabled() {
+		logger.Warn("включено внесение сбоев (chaos), не используйте в рабочих запусках")
+	}
+
+	// режим прокси проверен в config.Validate, неизвестный режим отклонит client.Build
+	tcfg.ProxyMode = client.ProxyMode(cfg.Proxy.Mode)
+	switch tcfg.ProxyMode {
+	case client.ProxyList:
+		if tcfg.ProxyList, err = cfg.ProxyURLs(); err != nil {
+			return nil, nil, err
+		}
+	case client.ProxyRotation:
+		if tcfg.RotationURL, err = cfg.RotationProxyURL(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	transport, err := client.Build(httpClient, tcfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка сборки transport слоя: %w", err)
+	}
+	svc := kuper.NewKuperService(transport, kuper.WithLogger(logger), kuper.WithBaseURL(baseURL))
+	closeFn := func() {
+		if err := cookies.Save(); err != nil {
+			logger.Warn("не удалось сохранить cookie", "err", err)
+		}
+	}
+	return svc, closeFn, nil
+}
+
+// headerProfiles профили заголовков из headers.profiles_file или встроенные
+func headerProfiles(cfg *config.Config) (*client.HeaderProfileSet, error) {
+	var profiles []client.HeaderProfile
+	if cfg.Headers.ProfilesFile != "" {
+		var err error
+		if profiles, err = client.LoadHeaderProfiles(cfg.Headers.ProfilesFile); err != nil {
+			return nil, err
+		}
+	}
+	return client.NewHeaderProfileSet(profiles, cfg.Headers.Profile)
+}
@@ -0,0 +1,176 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Статусы запуска в Summary
+const (
+	StatusOK      = "ok"
+	StatusPartial = "partial" // *PartialError
+	StatusFailed  = "failed"
+)
+
+// Summary итоги запуска Run; пишется в output.directory как summary_{store_id}.json
+type Summary struct {
+	RunID        string    `json:"run_id"`
+	StoreID      int       `json:"store_id"`
+	Retailer     string    `json:"retailer,omitempty"`
+	StoreAddress string    `json:"store_address,omitempty"`
+	StartedAt    time.Time `json:"started_at"`
+	FinishedAt   time.Time `json:"finished_at"`
+	Duration     float64   `json:"duration_seconds"`
+	Status       string    `json:"status"`
+	Error        string    `json:"error,omitempty"`
+
+	Rows          int `json:"rows"`
+	Pages         int `json:"pages"`
+	Retries       int `json:"retries"`
+	ProxyFailures int `json:"proxy_failures"`
+
+	Quality QualitySummary `json:"quality"`
+
+	Departments []DepartmentSummary `json:"departments"`
+	NotFound    []string            `json:"not_found,omitempty"` // элементы departments.names без совпадений
+	Filtered    []FilteredSummary   `json:"filtered,omitempty"`  // отброшены departments.exclude/min_products/max_products
+	Errors      []string            `json:"errors,omitempty"`    // пропущенные отделы и страницы
+}
+
+// DepartmentSummary итоги обхода одного отдела
+type DepartmentSummary struct {
+	Slug          string  `json:"slug"`
+	Name          string  `json:"name"`
+	File          string  `json:"file"`
+	Rows          int     `json:"rows"`
+	Expected      int     `json:"expected"` // products_count из категорий магазина
+	Pages         int     `json:"pages"`
+	SkippedPages  int     `json:"skipped_pages"`
+	InvalidRows   int     `json:"invalid_rows"` // строки с нарушениями quality.rules (кроме outlier)
+	DroppedRows   int     `json:"dropped_rows"`
+	Retries       int     `json:"retries"`
+	ProxyFailures int     `json:"proxy_failures"`
+	Duration      float64 `json:"duration_seconds"`
+	Error         string  `json:"error,omitempty"`
+}
+
+// maxReportedViolations сколько нарушений попадает в Summary; счётчики по правилам — полные
+const maxReportedViolations = 100
+
+// QualitySummary итоги проверки товаров правилами quality.rules
+type QualitySummary struct {
+	Checked    int                 `json:"checked_rows"`
+	Invalid    int                 `json:"invalid_rows"`
+	Dropped    int                 `json:"dropped_rows"`
+	ByRule     map[QualityRule]int `json:"by_rule,omitempty"`
+	Violations []Violation         `json:"violations,omitempty"` // первые maxReportedViolations
+}
+
+func (q *QualitySummary) add(res deptResult) {
+	q.Checked += res.checked
+	q.Invalid += res.invalid
+	q.Dropped += res.dropped
+	for _, v := range res.violations {
+		if q.ByRule == nil {
+			q.ByRule = make(map[QualityRule]int)
+		}
+		q.ByRule[v.Rule]++
+		if len(q.Violations) < maxReportedViolations {
+			q.Violations = append(q.Violations, v)
+		}
+	}
+}
+
+// FilteredSummary отдел, отброшенный фильтрами departments
+type FilteredSummary struct {
+	Slug   string `json:"slug"`
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// finish заполняет итоговые поля по результату Run
+func (s *Summary) finish(err error) {
+	s.FinishedAt = time.Now()
+	s.Duration = s.FinishedAt.Sub(s.StartedAt).Seconds()
+
+	s.Rows, s.Pages, s.Retries, s.ProxyFailures = 0, 0, 0, 0
+	for _, d := range s.Departments {
+		s.Rows += d.Rows
+		s.Pages += d.Pages
+		s.Retries += d.Retries
+		s.ProxyFailures += d.ProxyFailures
+	}
+
+	var partial *PartialError
+	switch {
+	case err == nil:
+		s.Status = StatusOK
+	case errors.As(err, &partial):
+		s.Status = StatusPartial
+	default:
+		s.Status = StatusFailed
+		s.Error = err.Error()
+	}
+}
+
+// SummaryPath путь к файлу итогов запуска в dir
+func SummaryPath(dir string, storeID int) string {
+	return filepath.Join(dir, fmt.Sprintf("summary_%d.json", storeID))
+}
+
+// WriteFile пишет итоги в JSON; файл перезаписывается каждым запуском, как и файлы с товарами
+func (s *Summary) WriteFile(path string) error {
+	if err := ensureDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// WriteTable выводит итоги таблицей по отделам
+func (s *Summary) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ОТДЕЛ\tСТРОК\tОЖИДАЛОСЬ\tСТРАНИЦ\tПРОПУЩЕНО СТР.\tС НАРУШЕНИЯМИ\tРЕТРАЕВ\tОШИБОК ПРОКСИ\tВРЕМЯ\tОШИБКА")
+	for _, d := range s.Departments {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%s\t%s\n",
+			d.Slug, d.Rows, d.Expected, d.Pages, d.SkippedPages, d.InvalidRows, d.Retries, d.ProxyFailures,
+			secondsString(d.Duration), d.Error)
+	}
+	fmt.Fprintf(tw, "ИТОГО\t%d\t\t%d\t\t%d\t%d\t%d\t%s\t\n",
+		s.Rows, s.Pages, s.Quality.Invalid, s.Retries, s.ProxyFailures, secondsString(s.Duration))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(s.Quality.ByRule) > 0 {
+		rules := make([]string, 0, len(s.Quality.ByRule))
+		for r, n := range s.Quality.ByRule {
+			rules = append(rules, fmt.Sprintf("%s=%d", r, n))
+		}
+		sort.Strings(rules)
+		fmt.Fprintf(w, "нарушения качества: %s; отброшено строк: %d\n", strings.Join(rules, " "), s.Quality.Dropped)
+	}
+	if len(s.NotFound) > 0 {
+		fmt.Fprintf(w, "не найдены: %s\n", strings.Join(s.NotFound, "; "))
+	}
+	for _, f := range s.Filtered {
+		fmt.Fprintf(w, "отброшен %s: %s\n", f.Slug, f.Reason)
+	}
+	_, err := fmt.Fprintf(w, "статус: %s\n", s.Status)
+	return err
+}
+
+func secondsString(sec float64) string {
+	return (time.Duration(sec * float64(time.Second))).Round(time.Millisecond).String()
+}
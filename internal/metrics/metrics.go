@@ -0,0 +1,314 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Registry набор метрик в формате Prometheus text exposition (без внешних зависимостей)
+type Registry struct {
+	mu      sync.Mutex
+	metrics map[string]collector
+}
+
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]collector)}
+}
+
+func (r *Registry) register(name string, c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.metrics[name]; ok {
+		panic("metrics: duplicate metric " + name)
+	}
+	r.metrics[name] = c
+}
+
+// WriteText пишет все метрики, отсортированные по имени
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.metrics))
+	for n := range r.metrics {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	cs := make([]collector, 0, len(names))
+	for _, n := range names {
+		cs = append(cs, r.metrics[n])
+	}
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range cs {
+		c.write(bw)
+	}
+	return bw.Flush()
+}
+
+// WriteFile атомарно сохраняет метрики в файл (для node_exporter textfile collector)
+func (r *Registry) WriteFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := r.WriteText(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Handler отдаёт метрики по HTTP (/metrics)
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = r.WriteText(w)
+	})
+}
+
+// vec общая часть всех метрик с метками
+type vec struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+
+	mu     sync.Mutex
+	series map[string][]string // ключ -> значения меток
+}
+
+func newVec(name, help, kind string, labels []string) vec {
+	return vec{name: name, help: help, kind: kind, labels: labels, series: make(map[string][]string)}
+}
+
+func (v *vec) key(values []string) string {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d labels, got %d", v.name, len(v.labels), len(values)))
+	}
+	k := strings.Join(values, "\xff")
+	if _, ok := v.series[k]; !ok {
+		v.series[k] = append([]string(nil), values...)
+	}
+	return k
+}
+
+func (v *vec) sortedKeys() []string {
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (v *vec) header(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, v.help, v.name, v.kind)
+}
+
+func (v *vec) labelString(values []string, extra ...string) string {
+	parts := make([]string, 0, len(values)+len(extra)/2)
+	for i, l := range v.labels {
+		parts = append(parts, l+`="`+escape(values[i])+`"`)
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		parts = append(parts, extra[i]+`="`+escape(extra[i+1])+`"`)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func escape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return strings.ReplaceAll(s, `"`, `\"`)
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// CounterVec монотонно растущий счётчик
+type CounterVec struct {
+	vec
+	values map[string]float64
+}
+
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{vec: newVec(name, help, "counter", labels), values: make(map[string]float64)}
+	r.register(name, c)
+	return c
+}
+
+func (c *CounterVec) Inc(labels ...string) { c.Add(1, labels...) }
+
+func (c *CounterVec) Add(delta float64, labels ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[c.key(labels)] += delta
+}
+
+func (c *CounterVec) Value(labels ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[strings.Join(labels, "\xff")]
+}
+
+// Sum сумма по всем сериям
+func (c *CounterVec) Sum() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var s float64
+	for _, v := range c.values {
+		s += v
+	}
+	return s
+}
+
+// SumWhere сумма по сериям, значения меток которых (в порядке объявления) подходят под match
+func (c *CounterVec) SumWhere(match func(labels []string) bool) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var s float64
+	for k, v := range c.values {
+		if match(c.series[k]) {
+			s += v
+		}
+	}
+	return s
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.header(w)
+	for _, k := range c.sortedKeys() {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, c.labelString(c.series[k]), formatFloat(c.values[k]))
+	}
+}
+
+// GaugeVec произвольное значение
+type GaugeVec struct {
+	vec
+	values map[string]float64
+}
+
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{vec: newVec(name, help, "gauge", labels), values: make(map[string]float64)}
+	r.register(name, g)
+	return g
+}
+
+func (g *GaugeVec) Set(v float64, labels ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.values[g.key(labels)] = v
+}
+
+func (g *GaugeVec) Add(delta float64, labels ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.values[g.key(labels)] += delta
+}
+
+func (g *GaugeVec) write(w *bufio.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.header(w)
+	for _, k := range g.sortedKeys() {
+		fmt.Fprintf(w, "%s%s %s\n", g.name, g.labelString(g.series[k]), formatFloat(g.values[k]))
+	}
+}
+
+// DefaultBuckets границы для длительностей в секундах
+var DefaultBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+type histogram struct {
+	counts []uint64 // по бакетам, не накопительно
+	sum    float64
+	count  uint64
+}
+
+// HistogramVec распределение значений по бакетам
+type HistogramVec struct {
+	vec
+	buckets []float64
+	values  map[string]*histogram
+}
+
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	h := &HistogramVec{
+		vec:     newVec(name, help, "histogram", labels),
+		buckets: append([]float64(nil), buckets...),
+		values:  make(map[string]*histogram),
+	}
+	sort.Float64s(h.buckets)
+	r.register(name, h)
+	return h
+}
+
+func (h *HistogramVec) Observe(v float64, labels ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	k := h.key(labels)
+	hs, ok := h.values[k]
+	if !ok {
+		hs = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.values[k] = hs
+	}
+	for i, b := range h.buckets {
+		if v <= b {
+			hs.counts[i]++
+			break
+		}
+	}
+	hs.sum += v
+	hs.count++
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.header(w)
+	for _, k := range h.sortedKeys() {
+		labels := h.series[k]
+		hs := h.values[k]
+
+		var cum uint64
+		for i, b := range h.buckets {
+			cum += hs.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelString(labels, "le", formatFloat(b)), cum)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelString(labels, "le", "+Inf"), hs.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.labelString(labels), formatFloat(hs.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.labelString(labels), hs.count)
+	}
+}
@@ -0,0 +1,224 @@
+// Package money денежные суммы в копейках: цены из ответа API без ошибок округления float64
+package money
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency валюта, если в ответе она не указана
+const DefaultCurrency = "RUB"
+
+// Money сумма в минорных единицах (копейках); нулевое значение — «цены нет»
+type Money struct {
+	minor    int64
+	currency string
+	valid    bool
+}
+
+// New сумма из копеек
+func New(minor int64, currency string) Money {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	return Money{minor: minor, currency: strings.ToUpper(currency), valid: true}
+}
+
+func (m Money) Valid() bool      { return m.valid }
+func (m Money) Minor() int64     { return m.minor }
+func (m Money) Currency() string { return m.currency }
+
+// Float64 только для сравнений и отношений, не для записи
+func (m Money) Float64() float64 { return float64(m.minor) / 100 }
+
+// Parse сумма из значения JSON: число, строка («89.99», «89,99», «1 299,00 ₽») или объект {amount|value, currency}
+func Parse(v any) (Money, error) {
+	switch t := v.(type) {
+	case float64:
+		if math.IsNaN(t) || math.IsInf(t, 0) {
+			return Money{}, fmt.Errorf("некорректная сумма %v", t)
+		}
+		// кратчайшее десятичное представление: 89.99, а не 89.99000000000001
+		return parseDecimal(strconv.FormatFloat(t, 'f', -1, 64), "")
+	case int:
+		return New(int64(t)*100, ""), nil
+	case int64:
+		return New(t*100, ""), nil
+	case json.Number:
+		// число JSON однозначно: точка всегда дробная часть
+		m, err := parseNumber(t.String(), "")
+		if err != nil {
+			return Money{}, fmt.Errorf("цена %q — не число", t.String())
+		}
+		return m, nil
+	case string:
+		return ParseString(t)
+	case map[string]any:
+		cur, _ := t["currency"].(string)
+		for _, k := range []string{"amount", "value"} {
+			if a, ok := t[k]; ok && a != nil {
+				m, err := Parse(a)
+				if err != nil {
+					return Money{}, err
+				}
+				if cur != "" {
+					m.currency = strings.ToUpper(cur)
+				}
+				return m, nil
+			}
+		}
+		return Money{}, fmt.Errorf("в объекте цены нет amount/value")
+	case nil:
+		return Money{}, fmt.Errorf("цена не указана")
+	}
+	return Money{}, fmt.Errorf("неподдерживаемый тип цены %T", v)
+}
+
+// ParseString сумма из строки: десятичная точка или запятая, пробелы между разрядами, знак валюты.
+// Последний разделитель — дробная часть, как в числах JSON: «89.990» — это 89.99, а не 89 990.
+// Разряды — пробелы («1 299,00»), разделитель перед другим («1,299.00», «1.299,00») и повторяющийся
+// разделитель («1,299,000»)
+func ParseString(s string) (Money, error) {
+	cur := ""
+	clean := strings.Map(func(r rune) rune {
+		switch {
+		case r >= '0' && r <= '9', r == '.', r == ',', r == '-', r == 'e', r == 'E', r == '+':
+			return r
+		case r == '₽':
+			cur = "RUB"
+		}
+		return -1 // пробелы, неразрывные пробелы, «руб.» и т.п.
+	}, s)
+	// точка от «руб.» или «р.» остаётся после чисел и не дробная часть
+	clean = strings.TrimRight(clean, ".,")
+	if clean == "" {
+		return Money{}, fmt.Errorf("цена %q — не число", s)
+	}
+
+	// «1.5e3» — запись числа, а не разряды
+	if strings.ContainsAny(clean, "eE") {
+		m, err := parseNumber(clean, cur)
+		if err != nil {
+			return Money{}, fmt.Errorf("цена %q — не число", s)
+		}
+		return m, nil
+	}
+
+	// последний из разделителей — дробная часть, остальные — разряды («1,299.00», «1.299,00»)
+	if i := strings.LastIndexAny(clean, ".,"); i >= 0 {
+		intPart := strings.NewReplacer(".", "", ",", "").Replace(clean[:i])
+		frac := clean[i+1:]
+		// «1,299,000»: тот же разделитель уже встречался — значит, и последний разделяет разряды
+		if len(frac) == 3 && strings.IndexByte(clean[:i], clean[i]) >= 0 {
+			intPart, frac = intPart+frac, ""
+		}
+		clean = intPart + "." + frac
+	}
+
+	m, err := parseDecimal(clean, cur)
+	if err != nil {
+		return Money{}, fmt.Errorf("цена %q — не число", s)
+	}
+	return m, nil
+}
+
+// parseNumber число в записи JSON/Go («89.99», «-1.5e3»): точка — всегда дробная часть
+func parseNumber(s, currency string) (Money, error) {
+	if strings.ContainsAny(s, "eE") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return Money{}, fmt.Errorf("некорректная сумма %q", s)
+		}
+		s = strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return parseDecimal(s, currency)
+}
+
+// parseDecimal «-123.456» -> копейки с округлением половины от нуля
+func parseDecimal(s, currency string) (Money, error) {
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimLeft(s, "-+")
+
+	if s == "" || s == "." {
+		return Money{}, fmt.Errorf("пустая сумма")
+	}
+	intPart, frac, _ := strings.Cut(s, ".")
+	if intPart == "" {
+		intPart = "0"
+	}
+
+	units, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil || units > math.MaxInt64/100-1 {
+		return Money{}, fmt.Errorf("некорректная сумма %q", s)
+	}
+	for _, r := range frac {
+		if r < '0' || r > '9' {
+			return Money{}, fmt.Errorf("некорректная сумма %q", s)
+		}
+	}
+
+	frac += "000"
+	cents, _ := strconv.ParseInt(frac[:2], 10, 64)
+	minor := units*100 + cents
+	if frac[2] >= '5' {
+		minor++
+	}
+	if neg {
+		minor = -minor
+	}
+	return New(minor, currency), nil
+}
+
+// Format сумма с двумя знаками после разделителя sep ("." или ","); без цены — пустая строка
+func (m Money) Format(sep string) string {
+	if !m.valid {
+		return ""
+	}
+	sign := ""
+	v := m.minor
+	if v < 0 {
+		sign, v = "-", -v
+	}
+	return fmt.Sprintf("%s%d%s%02d", sign, v/100, sep, v%100)
+}
+
+func (m Money) String() string { return m.Format(".") }
+
+// Div цена за единицу: сумма, делённая на q, с округлением до копейки
+func (m Money) Div(q float64) (Money, bool) {
+	if !m.valid || q <= 0 || math.IsNaN(q) || math.IsInf(q, 0) {
+		return Money{}, false
+	}
+	return Money{minor: int64(math.Round(float64(m.minor) / q)), currency: m.currency, valid: true}, true
+}
+
+// MarshalJSON число с двумя знаками (89.99), без цены — null
+func (m Money) MarshalJSON() ([]byte, error) {
+	if !m.valid {
+		return []byte("null"), nil
+	}
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON число, строка, null или пустая строка (нет цены)
+func (m *Money) UnmarshalJSON(b []byte) error {
+	var v any
+	dec := json.NewDecoder(strings.NewReader(string(b)))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+	if v == nil || v == "" {
+		*m = Money{}
+		return nil
+	}
+	parsed, err := Parse(v)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
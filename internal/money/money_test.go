@@ -0,0 +1,152 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseString(t *testing.T) {
+	tests := []struct {
+		in       string
+		minor    int64
+		currency string
+	}{
+		{"89.99", 8999, "RUB"},
+		{"89,99", 8999, "RUB"},
+		{"89", 8900, "RUB"},
+		{"89.9", 8990, "RUB"},
+		{"0.995", 100, "RUB"},
+		{"89,9951", 9000, "RUB"},
+		{"89.", 8900, "RUB"},
+		{"1 299,00 ₽", 129900, "RUB"},
+		{"1 299,50 руб.", 129950, "RUB"},
+		{"1,299.00", 129900, "RUB"},
+		{"1.299,00", 129900, "RUB"},
+		{"1 299", 129900, "RUB"},
+		{"1,299,000", 129900000, "RUB"},
+		{"1.299.000,50", 129900050, "RUB"},
+		// одиночный разделитель — всегда дробная часть, как в json.Number
+		{"89.990", 8999, "RUB"},
+		{"1,299", 130, "RUB"},
+		{"1.299", 130, "RUB"},
+		{"0,450", 45, "RUB"},
+		{"1234,567", 123457, "RUB"},
+		{"-5,5", -550, "RUB"},
+		{"1.5e3", 150000, "RUB"},
+		{".5", 50, "RUB"},
+	}
+	for _, tt := range tests {
+		m, err := ParseString(tt.in)
+		if err != nil {
+			t.Errorf("ParseString(%q): %v", tt.in, err)
+			continue
+		}
+		if !m.Valid() || m.Minor() != tt.minor || m.Currency() != tt.currency {
+			t.Errorf("ParseString(%q) = %d %s, ожидалось %d %s", tt.in, m.Minor(), m.Currency(), tt.minor, tt.currency)
+		}
+	}
+}
+
+func TestParseStringInvalid(t *testing.T) {
+	for _, in := range []string{"", "цена по запросу", ".", "1e", "1-2"} {
+		if m, err := ParseString(in); err == nil {
+			t.Errorf("ParseString(%q) = %v, ожидалась ошибка", in, m)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       any
+		minor    int64
+		currency string
+	}{
+		{"float64", 89.99, 8999, "RUB"},
+		{"float64 без ошибки округления", 0.1 + 0.2, 30, "RUB"},
+		{"int", 100, 10000, "RUB"},
+		{"int64", int64(7), 700, "RUB"},
+		// в json.Number точка всегда дробная часть, даже перед тремя цифрами
+		{"json.Number", json.Number("1.299"), 130, "RUB"},
+		{"json.Number с экспонентой", json.Number("1.2999e2"), 12999, "RUB"},
+		{"строка", "109,99", 10999, "RUB"},
+		{"amount", map[string]any{"amount": 89.5, "currency": "usd"}, 8950, "USD"},
+		{"value", map[string]any{"value": "12,30"}, 1230, "RUB"},
+	}
+	for _, tt := range tests {
+		m, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if m.Minor() != tt.minor || m.Currency() != tt.currency {
+			t.Errorf("%s: Parse(%v) = %d %s, ожидалось %d %s", tt.name, tt.in, m.Minor(), m.Currency(), tt.minor, tt.currency)
+		}
+	}
+
+	for _, in := range []any{nil, true, map[string]any{"currency": "RUB"}, json.Number("abc")} {
+		if _, err := Parse(in); err == nil {
+			t.Errorf("Parse(%#v): ожидалась ошибка", in)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		m    Money
+		sep  string
+		want string
+	}{
+		{New(8999, ""), ",", "89,99"},
+		{New(8999, ""), ".", "89.99"},
+		{New(5, ""), ",", "0,05"},
+		{New(-550, ""), ".", "-5.50"},
+		{Money{}, ",", ""},
+	}
+	for _, tt := range tests {
+		if got := tt.m.Format(tt.sep); got != tt.want {
+			t.Errorf("Format(%q) = %q, ожидалось %q", tt.sep, got, tt.want)
+		}
+	}
+}
+
+func TestDiv(t *testing.T) {
+	// 109,99 за 0,93 л
+	got, ok := New(10999, "").Div(0.93)
+	if !ok || got.Minor() != 11827 {
+		t.Errorf("Div = %v %v, ожидалось 118.27", got, ok)
+	}
+	for _, q := range []float64{0, -1} {
+		if _, ok := New(100, "").Div(q); ok {
+			t.Errorf("Div(%v): ожидался отказ", q)
+		}
+	}
+	if _, ok := (Money{}).Div(1); ok {
+		t.Error("Div без цены: ожидался отказ")
+	}
+}
+
+func TestJSON(t *testing.T) {
+	type row struct {
+		Price Money `json:"price"`
+		Unit  Money `json:"unit"`
+	}
+	b, err := json.Marshal(row{Price: New(8999, "")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"price":89.99,"unit":null}` {
+		t.Errorf("Marshal = %s", b)
+	}
+
+	var r row
+	if err := json.Unmarshal([]byte(`{"price":"1 299,00","unit":""}`), &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Price.Minor() != 129900 || r.Unit.Valid() {
+		t.Errorf("Unmarshal = %+v", r)
+	}
+	if err := json.Unmarshal([]byte(`{"price":89.90}`), &r); err != nil || r.Price.Minor() != 8990 {
+		t.Errorf("Unmarshal числа = %v, %v", r.Price, err)
+	}
+}
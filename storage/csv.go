@@ -3,14 +3,27 @@ package storage
 import (
 	"encoding/csv"
 	"os"
+	"strconv"
+	"time"
 )
 
+var csvHeader = []string{
+	"Имя товара", "Цена", "Ссылка", "Фасовка", "Цена за единицу", "Единица",
+	"ID магазина", "Сеть", "Slug отдела", "Отдел", "Подотдел", "Страница", "Позиция", "Время сбора",
+}
+
 type CSVWriter struct {
-	f *os.File
-	w *csv.Writer
+	f       *os.File
+	w       *csv.Writer
+	decimal string
 }
 
-func NewCSVWriter(path string) (*CSVWriter, error) {
+// NewCSVWriter decimal — разделитель дробной части цен, пусто — точка
+func NewCSVWriter(path, decimal string) (*CSVWriter, error) {
+	if decimal == "" {
+		decimal = "."
+	}
+
 	f, err := os.Create(path)
 	if err != nil {
 		return nil, err
@@ -21,17 +34,21 @@ func NewCSVWriter(path string) (*CSVWriter, error) {
 	w := csv.NewWriter(f)
 	w.Comma = ';'
 
-	if err := w.Write([]string{"Имя товара", "Цена", "Ссылка"}); err != nil {
+	if err := w.Write(csvHeader); err != nil {
 		_ = f.Close()
 		return nil, err
 	}
 	w.Flush()
 
-	return &CSVWriter{f: f, w: w}, nil
+	return &CSVWriter{f: f, w: w, decimal: decimal}, nil
 }
 
-func (c *CSVWriter) WriteRow(name, price, url string) error {
-	if err := c.w.Write([]string{name, price, url}); err != nil {
+func (c *CSVWriter) Write(r Row) error {
+	if err := c.w.Write([]string{
+		r.Name, r.Price.Format(c.decimal), r.URL, r.Pack, r.UnitPrice.Format(c.decimal), r.Unit,
+		intString(r.StoreID), r.Retailer, r.DepartmentSlug, r.DepartmentName, r.SubDepartment,
+		intString(r.Page), intString(r.Position), timeString(r.ScrapedAt),
+	}); err != nil {
 		return err
 	}
 	c.w.Flush()
@@ -43,3 +60,18 @@ func (c *CSVWriter) Close() error {
 	_ = c.w.Error()
 	return c.f.Close()
 }
+
+// intString пустая строка для нуля: в файлах старого формата колонки нет
+func intString(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+func timeString(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+)
+
+// JSONLWriter по одному JSON-объекту на строку
+type JSONLWriter struct {
+	f   *os.File
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func NewJSONLWriter(path string) (*JSONLWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &JSONLWriter{f: f, w: w, enc: enc}, nil
+}
+
+// jsonlRow строка с валютой цены
+type jsonlRow struct {
+	Row
+	Currency string `json:"currency,omitempty"`
+}
+
+func (j *JSONLWriter) Write(r Row) error {
+	if err := j.enc.Encode(jsonlRow{Row: r, Currency: r.Price.Currency()}); err != nil {
+		return err
+	}
+	return j.w.Flush()
+}
+
+func (j *JSONLWriter) Close() error {
+	_ = j.w.Flush()
+	return j.f.Close()
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"kuperparser/internal/money"
+)
+
+// ReadFile читает файл, записанный NewWriter в формате format; файла нет — пустой результат без ошибки
+func ReadFile(format, path string) ([]Row, error) {
+	ext, err := Ext(format)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if ext == "jsonl" {
+		return readJSONL(f)
+	}
+	return readCSV(f)
+}
+
+func readCSV(r io.Reader) ([]Row, error) {
+	br := bufio.NewReader(r)
+	// BOM, который пишет NewCSVWriter
+	if b, err := br.Peek(3); err == nil && string(b) == "\xEF\xBB\xBF" {
+		_, _ = br.Discard(3)
+	}
+
+	cr := csv.NewReader(br)
+	cr.Comma = ';'
+	cr.FieldsPerRecord = -1
+
+	var rows []Row
+	for i := 0; ; i++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return rows, err
+		}
+		if i == 0 || len(rec) < 3 {
+			continue // заголовок
+		}
+		// в файлах старого формата нет колонок фасовки и происхождения
+		rec = append(rec, make([]string, len(csvHeader)-min(len(rec), len(csvHeader)))...)
+		// цену, которую не удалось разобрать, оставляем пустой; так же с числами и временем
+		price, _ := money.ParseString(rec[1])
+		unitPrice, _ := money.ParseString(rec[4])
+		storeID, _ := strconv.Atoi(rec[6])
+		page, _ := strconv.Atoi(rec[11])
+		position, _ := strconv.Atoi(rec[12])
+		scrapedAt, _ := time.Parse(time.RFC3339, rec[13])
+		rows = append(rows, Row{
+			Name: rec[0], Price: price, URL: rec[2], Pack: rec[3], UnitPrice: unitPrice, Unit: rec[5],
+			Source: Source{
+				StoreID: storeID, Retailer: rec[7], DepartmentSlug: rec[8], DepartmentName: rec[9], SubDepartment: rec[10],
+				Page: page, Position: position, ScrapedAt: scrapedAt,
+			},
+		})
+	}
+}
+
+func readJSONL(r io.Reader) ([]Row, error) {
+	var rows []Row
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 4<<20)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		var row Row
+		if err := json.Unmarshal([]byte(line), &row); err != nil {
+			return rows, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, sc.Err()
+}
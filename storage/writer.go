@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"kuperparser/internal/money"
+)
+
+// Row строка файла с товарами
+type Row struct {
+	Name  string      `json:"name"`
+	Price money.Money `json:"price"`
+	URL   string      `json:"url"`
+
+	// фасовка из ответа API или названия («930 мл») и цена за кг/л/шт; пусто — фасовку определить не удалось
+	Pack      string      `json:"pack,omitempty"`
+	UnitPrice money.Money `json:"unit_price"`
+	Unit      string      `json:"unit,omitempty"` // кг | л | шт
+
+	Source
+}
+
+// Source откуда и когда взята строка
+type Source struct {
+	StoreID        int    `json:"store_id"`
+	Retailer       string `json:"retailer"`
+	DepartmentSlug string `json:"department_slug"`
+	DepartmentName string `json:"department_name"`
+	SubDepartment  string `json:"sub_department,omitempty"` // группа товаров внутри страницы отдела, если API её отдаёт
+
+	Page      int       `json:"page"`
+	Position  int       `json:"position,omitempty"` // место в выдаче отдела с 1, сквозное по страницам; 0 — неизвестно
+	ScrapedAt time.Time `json:"scraped_at"`
+}
+
+// Options оформление значений в файле
+type Options struct {
+	// Decimal разделитель дробной части цен в CSV: "," для русского Excel или "."; в JSONL цены — числа
+	Decimal string
+}
+
+// Writer файл с товарами одного отдела
+type Writer interface {
+	Write(r Row) error
+	Close() error
+}
+
+// Ext расширение файла для формата вывода (output.format); пусто — csv
+func Ext(format string) (string, error) {
+	switch strings.ToLower(format) {
+	case "", "csv":
+		return "csv", nil
+	case "json", "jsonl":
+		return "jsonl", nil
+	default:
+		return "", fmt.Errorf("неизвестный формат вывода %q (ожидается csv|jsonl)", format)
+	}
+}
+
+// NewWriter создаёт файл в формате format
+func NewWriter(format, path string, opts Options) (Writer, error) {
+	ext, err := Ext(format)
+	if err != nil {
+		return nil, err
+	}
+	if ext == "jsonl" {
+		return NewJSONLWriter(path)
+	}
+	return NewCSVWriter(path, opts.Decimal)
+}